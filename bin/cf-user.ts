@@ -13,8 +13,13 @@ const CDK_DEFAULT_REGION = get('CDK_DEFAULT_REGION').required().asString();
 const SERVICE = get('SERVICE').required().asString();
 const STAGE = get('STAGE').required().asString();
 
-const AUTHORIZER_FUNCTION_ARN = get("AUTHORIZER_FUNCTION_ARN").required().asString();
+const AUTHORIZER_FUNCTION_ARN = get('AUTHORIZER_FUNCTION_ARN').asString();
+const AUTH_ISSUER = get('AUTH_ISSUER').asString();
+const AUTH_AUDIENCE = get('AUTH_AUDIENCE').asString();
 const ISO_3166_CODE = get('ISO_3166_CODE').required().asString();
+const CORS_ALLOWED_ORIGINS = get('CORS_ALLOWED_ORIGINS').asArray(',');
+const CONFIG_PARAMETER_PATH = get('CONFIG_PARAMETER_PATH').asString();
+const FEATURE_FLAGS_PARAMETER = get('FEATURE_FLAGS_PARAMETER').asString();
 
 const appStackName = `${SERVICE}-${STAGE}-app`;
 
@@ -25,8 +30,13 @@ new AppStack(app, appStackName, {
 	service: SERVICE,
 	stage: STAGE,
 	authorizerFunctionArn: AUTHORIZER_FUNCTION_ARN,
+	authIssuer: AUTH_ISSUER,
+	authAudience: AUTH_AUDIENCE,
 	subscriptionEmail: 'aws_alarm@classifind.app',
 	iso3166Code: ISO_3166_CODE,
+	corsAllowedOrigins: CORS_ALLOWED_ORIGINS,
+	configParameterPath: CONFIG_PARAMETER_PATH,
+	featureFlagsParameter: FEATURE_FLAGS_PARAMETER,
 	env: {
 		account: CDK_DEFAULT_ACCOUNT,
 		region: CDK_DEFAULT_REGION,
@@ -29,6 +29,9 @@ func main() {
 
 	fmt.Printf("%d Lambda entrypoints found...\n", len(dirs))
 
+	version := getBuildVersion()
+	fmt.Printf("Build version %v...\n", version)
+
 	for i := 0; i < len(dirs); i++ {
 		fmt.Printf("Tidying Lambda %d of %d...\n", i+1, len(dirs))
 		err = runModTidy(dirs[i])
@@ -38,7 +41,7 @@ func main() {
 		}
 
 		fmt.Printf("Building Lambda %d of %d...\n", i+1, len(dirs))
-		err = buildMainGoFile(dirs[i])
+		err = buildMainGoFile(dirs[i], version)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
@@ -99,8 +102,23 @@ func runModTidy(path string) error {
 	return nil
 }
 
-func buildMainGoFile(path string) error {
-	cmd := exec.Command("go", "build", "-trimpath", "-ldflags=-s -w", "-o", "bootstrap")
+// getBuildVersion returns the BUILD_VERSION environment variable, or the short commit hash when unset.
+func getBuildVersion() string {
+	if version := os.Getenv("BUILD_VERSION"); len(version) > 0 {
+		return version
+	}
+
+	output, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "dev"
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+func buildMainGoFile(path string, version string) error {
+	ldflags := fmt.Sprintf("-ldflags=-s -w -X cf-user/core.BuildVersion=%v", version)
+	cmd := exec.Command("go", "build", "-trimpath", ldflags, "-o", "bootstrap")
 	cmd.Dir = path
 	cmd.Env = append(os.Environ(),
 		"GOOS=linux",
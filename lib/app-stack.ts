@@ -19,7 +19,12 @@ export interface AppStackProps extends cdk.StackProps {
 	stage: string;
 	service: string;
 	subscriptionEmail: string;
-	authorizerFunctionArn: string;
+	authorizerFunctionArn?: string;
+	authIssuer?: string;
+	authAudience?: string;
+	corsAllowedOrigins?: string[];
+	configParameterPath?: string;
+	featureFlagsParameter?: string;
 	iso3166Code: string;
 }
 
@@ -112,6 +117,51 @@ export class AppStack extends cdk.Stack {
 			usersTable
 		);
 		usersTable.grantReadData(getUser);
+		this.grantRateLimitWrites(getUser, usersTable);
+
+		const getCurrentUser = this.createLambda(
+			'GetCurrentUser',
+			'get-current-user',
+			props,
+			snsTopic,
+			usersTable
+		);
+		usersTable.grantReadData(getCurrentUser);
+		this.grantRateLimitWrites(getCurrentUser, usersTable);
+
+		const updateCurrentUser = this.createLambda(
+			'UpdateCurrentUser',
+			'update-current-user',
+			props,
+			snsTopic,
+			usersTable
+		);
+		// Callers need no role to update their own profile, so the function may only update user items
+		usersTable.grantReadData(updateCurrentUser);
+		updateCurrentUser.addToRolePolicy(
+			new iam.PolicyStatement({
+				actions: ['dynamodb:UpdateItem'],
+				resources: [usersTable.tableArn],
+				conditions: {
+					'ForAllValues:StringLike': {
+						'dynamodb:LeadingKeys': ['USER#*'],
+					},
+				},
+			})
+		);
+		this.grantRateLimitWrites(updateCurrentUser, usersTable);
+
+		const healthCheck = this.createLambda(
+			'HealthCheck',
+			'health-check',
+			props,
+			snsTopic,
+			usersTable
+		);
+		// Read access includes DescribeTable for the deep check. As the endpoints are unauthenticated,
+		// writes are limited to the rate limit buckets.
+		usersTable.grantReadData(healthCheck);
+		this.grantRateLimitWrites(healthCheck, usersTable);
 
 		// Routes
 		const v1 = api.root.addResource('v1');
@@ -120,6 +170,28 @@ export class AppStack extends cdk.Stack {
 		const usersV1 = v1.addResource('users');
 		usersV1.addMethod('POST', new apigateway.LambdaIntegration(createUser));
 
+		const currentUserV1 = usersV1.addResource('me');
+		currentUserV1.addMethod(
+			'GET',
+			new apigateway.LambdaIntegration(getCurrentUser)
+		);
+		currentUserV1.addMethod(
+			'PUT',
+			new apigateway.LambdaIntegration(updateCurrentUser)
+		);
+
+		// Health checks are unauthenticated, and rate limited by source IP in the handler
+		const healthV1 = v1.addResource('health', {
+			defaultMethodOptions: {
+				authorizer: undefined,
+				authorizationType: apigateway.AuthorizationType.NONE,
+			},
+		});
+		healthV1.addMethod('GET', new apigateway.LambdaIntegration(healthCheck));
+		healthV1
+			.addResource('deep')
+			.addMethod('GET', new apigateway.LambdaIntegration(healthCheck));
+
 		const userIdV1 = usersV1.addResource('{userId}');
 		userIdV1.addMethod('GET', new apigateway.LambdaIntegration(getUser));
 		userIdV1.addMethod('PUT', new apigateway.LambdaIntegration(updateUser));
@@ -143,11 +215,14 @@ export class AppStack extends cdk.Stack {
 	}
 
 	private createAuthorizer(props: AppStackProps): apigateway.TokenAuthorizer {
-		const authorizerFunction = lambda.Function.fromFunctionArn(
-			this,
-			'LambdaAuthorizer',
-			props.authorizerFunctionArn
-		);
+		// An external authorizer takes precedence, otherwise the authorizer in src/authorizer is deployed
+		const authorizerFunction = props.authorizerFunctionArn
+			? lambda.Function.fromFunctionArn(
+					this,
+					'LambdaAuthorizer',
+					props.authorizerFunctionArn
+			  )
+			: this.createAuthorizerFunction(props);
 
 
 		const authorizer = new apigateway.TokenAuthorizer(this, "Auth0Authorizer", {
@@ -159,6 +234,42 @@ export class AppStack extends cdk.Stack {
 		return authorizer;
 	}
 
+	private createAuthorizerFunction(props: AppStackProps): lambda.IFunction {
+		if (!props.authIssuer || !props.authAudience) {
+			throw new Error(
+				'AUTH_ISSUER and AUTH_AUDIENCE are required when AUTHORIZER_FUNCTION_ARN is not set'
+			);
+		}
+
+		const authorizerFunction = new lambda.Function(this, 'Authorizer', {
+			functionName: `${this.stackName}-Authorizer`,
+			code: lambda.Code.fromAsset('./dist/authorizer/bootstrap.zip'),
+			handler: 'bootstrap',
+			runtime: lambda.Runtime.PROVIDED_AL2,
+			architecture: lambda.Architecture.ARM_64,
+			timeout: cdk.Duration.seconds(10),
+			memorySize: 256,
+			environment: {
+				SERVICE: props.service,
+				STAGE: props.stage,
+				AUTH_ISSUER: props.authIssuer,
+				AUTH_AUDIENCE: props.authAudience,
+			},
+			tracing: lambda.Tracing.ACTIVE,
+		});
+
+		new logs.LogGroup(this, 'AuthorizerLogGroup', {
+			logGroupName: `/aws/lambda/${authorizerFunction.functionName}`,
+			retention:
+				props.stage === 'prod'
+					? logs.RetentionDays.ONE_YEAR
+					: logs.RetentionDays.ONE_WEEK,
+			removalPolicy: cdk.RemovalPolicy.DESTROY,
+		});
+
+		return authorizerFunction;
+	}
+
 	private createApiGateway(props: AppStackProps, snsTopic: sns.Topic): apigateway.RestApi {
 		const authorizer = this.createAuthorizer(props);
 
@@ -193,6 +304,12 @@ export class AppStack extends cdk.Stack {
 			removalPolicy: cdk.RemovalPolicy.DESTROY,
 		});
 
+		// API Gateway answers preflight requests itself and only matches exact origins, while the
+		// handlers apply the full CORS policy, including wildcard subdomains, to every response
+		const preflightOrigins = (props.corsAllowedOrigins ?? []).filter(
+			(origin) => !origin.includes('*')
+		);
+
 		const api = new apigateway.RestApi(this, 'api-gateway', {
 			restApiName: this.stackName,
 			endpointConfiguration: {
@@ -213,11 +330,24 @@ export class AppStack extends cdk.Stack {
 				authorizer: authorizer,
 			},
 			policy: apiResourcePolicy,
+			// Compressed, CBOR and MessagePack bodies are returned base64 encoded by the handlers. Request
+			// bodies are then base64 encoded too, which the handlers decode before parsing.
+			binaryMediaTypes: ['*/*'],
 			defaultCorsPreflightOptions: {
 				allowMethods: apigateway.Cors.ALL_METHODS,
-				allowOrigins: apigateway.Cors.ALL_ORIGINS,
-				allowHeaders: apigateway.Cors.DEFAULT_HEADERS,
-				maxAge: cdk.Duration.seconds(60),
+				allowOrigins:
+					preflightOrigins.length > 0
+						? preflightOrigins
+						: apigateway.Cors.ALL_ORIGINS,
+				allowCredentials: preflightOrigins.length > 0,
+				allowHeaders: [
+					...apigateway.Cors.DEFAULT_HEADERS,
+					'X-Correlation-Id',
+					'Idempotency-Key',
+					'Prefer',
+					'X-Debug-Log',
+				],
+				maxAge: cdk.Duration.minutes(10),
 			},
 			cloudWatchRole: false,
 		});
@@ -287,6 +417,7 @@ export class AppStack extends cdk.Stack {
 			removalPolicy: cdk.RemovalPolicy.DESTROY,
 			encryption: ddb.TableEncryption.AWS_MANAGED,
 			stream: ddb.StreamViewType.NEW_AND_OLD_IMAGES,
+			timeToLiveAttribute: 'ExpiresAt',
 		});
 
 		table.addGlobalSecondaryIndex({
@@ -313,6 +444,19 @@ export class AppStack extends cdk.Stack {
 			},
 		});
 
+		// Sparse index of users linked to an identity provider subject
+		table.addGlobalSecondaryIndex({
+			indexName: 'GSI3',
+			partitionKey: {
+				name: 'GSI3PK',
+				type: ddb.AttributeType.STRING,
+			},
+			sortKey: {
+				name: 'GSI3SK',
+				type: ddb.AttributeType.STRING,
+			},
+		});
+
 		return table;
 	}
 
@@ -335,6 +479,20 @@ export class AppStack extends cdk.Stack {
 				SERVICE: props.service,
 				STAGE: props.stage,
 				USER_TABLE_NAME: userTable.tableName,
+				LOG_REDACTION_POLICY: this.isProdStage(props.stage)
+					? 'strict'
+					: 'standard',
+				LOG_LEVEL: this.isProdStage(props.stage) ? 'info' : 'debug',
+				LOG_DEBUG_SAMPLE_RATE: this.isProdStage(props.stage) ? '0.01' : '0',
+				...(props.corsAllowedOrigins
+					? { CORS_ALLOWED_ORIGINS: props.corsAllowedOrigins.join(',') }
+					: {}),
+				...(props.configParameterPath
+					? { CONFIG_PARAMETER_PATH: props.configParameterPath }
+					: {}),
+				...(props.featureFlagsParameter
+					? { FEATURE_FLAGS_PARAMETER: props.featureFlagsParameter }
+					: {}),
 			},
 			tracing: lambda.Tracing.ACTIVE,
 			currentVersionOptions: {
@@ -342,6 +500,30 @@ export class AppStack extends cdk.Stack {
 			},
 		});
 
+		// Settings stored as SSM parameters are read once at cold start
+		if (props.configParameterPath) {
+			newLambda.addToRolePolicy(
+				new iam.PolicyStatement({
+					actions: ['ssm:GetParametersByPath'],
+					resources: [
+						`arn:aws:ssm:${this.region}:${this.account}:parameter${props.configParameterPath}`,
+					],
+				})
+			);
+		}
+
+		// Feature flags are refreshed from SSM while the function is warm
+		if (props.featureFlagsParameter) {
+			newLambda.addToRolePolicy(
+				new iam.PolicyStatement({
+					actions: ['ssm:GetParameter'],
+					resources: [
+						`arn:aws:ssm:${this.region}:${this.account}:parameter${props.featureFlagsParameter}`,
+					],
+				})
+			);
+		}
+
 		new logs.LogGroup(this, `${methodName}LogGroup`, {
 			logGroupName: `/aws/lambda/${newLambda.functionName}`,
 			retention:
@@ -373,6 +555,36 @@ export class AppStack extends cdk.Stack {
 
 		newErrors.addAlarmAction(new actions.SnsAction(snsTopic));
 
+		// Client error counts are emitted by the function handler using the Embedded Metric Format
+		const clientErrors = new cloudwatch.Alarm(
+			this,
+			`${methodName}ClientErrors`,
+			{
+				alarmName: `${this.stackName}-${methodName}-4xx`,
+				alarmDescription: '4XX responses >= 25 in 5 minutes',
+				metric: new cloudwatch.Metric({
+					namespace: props.service,
+					metricName: 'Response4XX',
+					dimensionsMap: {
+						Service: props.service,
+						Stage: props.stage,
+						FunctionName: newLambda.functionName,
+					},
+					statistic: 'Sum',
+					period: cdk.Duration.minutes(5),
+				}),
+				threshold: 25,
+				evaluationPeriods: 1,
+				actionsEnabled: true,
+				treatMissingData: cloudwatch.TreatMissingData.NOT_BREACHING,
+				comparisonOperator:
+					cloudwatch.ComparisonOperator
+						.GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+			}
+		);
+
+		clientErrors.addAlarmAction(new actions.SnsAction(snsTopic));
+
 		const lambdaDeploymentConfig = this.isProdStage(props.stage)
 			? codeDeploy.LambdaDeploymentConfig.CANARY_10PERCENT_10MINUTES
 			: codeDeploy.LambdaDeploymentConfig.ALL_AT_ONCE;
@@ -390,6 +602,21 @@ export class AppStack extends cdk.Stack {
 		return newAlias;
 	}
 
+	// Lets a function with read access store its rate limit buckets, without write access to user items
+	private grantRateLimitWrites(fn: lambda.IFunction, table: ddb.Table): void {
+		fn.addToRolePolicy(
+			new iam.PolicyStatement({
+				actions: ['dynamodb:PutItem'],
+				resources: [table.tableArn],
+				conditions: {
+					'ForAllValues:StringLike': {
+						'dynamodb:LeadingKeys': ['RATELIMIT#*'],
+					},
+				},
+			})
+		);
+	}
+
 	private isCicdStage(stage: string): boolean {
 		return ['rd', 'dev', 'staging', 'prod'].includes(stage);
 	}
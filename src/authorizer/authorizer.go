@@ -0,0 +1,174 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	cfconfig "cf-user/core/config"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// API Gateway turns this exact error message into a 401 response.
+var ErrUnauthorized = errors.New("Unauthorized")
+
+type Config struct {
+	Issuer   string
+	Audience string
+	JwksUrl  string
+	JwksTtl  time.Duration
+	// Custom claim holding an array of permissions, merged with the scopes in the "scope" claim
+	PermissionsClaim string
+}
+
+type Authorizer struct {
+	config Config
+	jwks   *JwksCache
+	parser *jwt.Parser
+}
+
+var Instance *Authorizer
+
+// LoadConfig returns the authorizer settings of the process wide AUTH_* config.
+func LoadConfig() Config {
+	settings := cfconfig.CurrentAuth()
+
+	return Config{
+		Issuer:           settings.Issuer,
+		Audience:         settings.Audience,
+		JwksUrl:          settings.JwksUrl,
+		JwksTtl:          settings.JwksTtl,
+		PermissionsClaim: settings.PermissionsClaim,
+	}
+}
+
+func CreateAuthorizer(config Config, httpClient *http.Client) (*Authorizer, error) {
+	if len(config.Issuer) == 0 || len(config.Audience) == 0 || len(config.JwksUrl) == 0 {
+		return nil, fmt.Errorf("issuer, audience and jwks url are required")
+	}
+
+	return &Authorizer{
+		config: config,
+		jwks:   CreateJwksCache(config.JwksUrl, config.JwksTtl, httpClient),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{"RS256", "ES256"}),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithAudience(config.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(30*time.Second),
+		),
+	}, nil
+}
+
+func InitLambda(httpClient *http.Client) {
+	authorizer, err := CreateAuthorizer(LoadConfig(), httpClient)
+	if err != nil {
+		log.Panicf("Unable to create authorizer, %v", err.Error())
+	}
+
+	Instance = authorizer
+}
+
+func Handler(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	return Instance.Authorize(ctx, request)
+}
+
+// Authorize validates the bearer token and allows the caller on every route of the API stage, so
+// the policy cached by API Gateway for the token applies to all routes rather than just this one.
+// The permissions and requesterOid context values are what LambdaRole and the handlers read.
+func (authorizer *Authorizer) Authorize(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	tokenString, ok := strings.CutPrefix(request.AuthorizationToken, "Bearer ")
+	if !ok || len(tokenString) == 0 {
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := authorizer.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return authorizer.jwks.GetKey(ctx, kid)
+	})
+	if err != nil || !token.Valid {
+		slog.Default().Warn("Rejected token", "Error", fmt.Sprint(err))
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || len(subject) == 0 {
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+	}
+
+	permissions := authorizer.permissions(claims)
+
+	authContext := map[string]interface{}{
+		"requesterOid": subject,
+		"permissions":  strings.Join(permissions, ","),
+	}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		authContext["expiresAt"] = expiresAt.Unix()
+	}
+
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID: subject,
+		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+			Version: "2012-10-17",
+			Statement: []events.IAMPolicyStatement{
+				{
+					Action:   []string{"execute-api:Invoke"},
+					Effect:   "Allow",
+					Resource: []string{stageWildcardArn(request.MethodArn)},
+				},
+			},
+		},
+		Context: authContext,
+	}, nil
+}
+
+func (authorizer *Authorizer) permissions(claims jwt.MapClaims) []string {
+	unique := make(map[string]bool)
+
+	if scope, ok := claims["scope"].(string); ok {
+		for _, value := range strings.Fields(scope) {
+			unique[value] = true
+		}
+	}
+
+	switch values := claims[authorizer.config.PermissionsClaim].(type) {
+	case []interface{}:
+		for _, value := range values {
+			if permission, ok := value.(string); ok && len(permission) > 0 {
+				unique[permission] = true
+			}
+		}
+	case string:
+		for _, value := range strings.Fields(strings.ReplaceAll(values, ",", " ")) {
+			unique[value] = true
+		}
+	}
+
+	permissions := make([]string, 0, len(unique))
+	for permission := range unique {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	return permissions
+}
+
+// stageWildcardArn turns arn:aws:execute-api:region:account:api/stage/METHOD/path into
+// arn:aws:execute-api:region:account:api/stage/*/*.
+func stageWildcardArn(methodArn string) string {
+	parts := strings.SplitN(methodArn, "/", 3)
+	if len(parts) < 2 {
+		return methodArn
+	}
+
+	return parts[0] + "/" + parts[1] + "/*/*"
+}
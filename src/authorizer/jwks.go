@@ -0,0 +1,182 @@
+package authorizer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Unknown key ids trigger a refresh to pick up rotated keys, but no more often than this.
+const minJwksRefreshInterval = 30 * time.Second
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// JwksCache holds the signing keys published by the identity provider. Keys are kept across
+// invocations of a warm Lambda and refetched once the ttl expires or an unknown key id is seen.
+type JwksCache struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+	keys       map[string]crypto.PublicKey
+	fetchedAt  time.Time
+	mutex      sync.Mutex
+}
+
+func CreateJwksCache(url string, ttl time.Duration, httpClient *http.Client) *JwksCache {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return &JwksCache{
+		url:        url,
+		ttl:        ttl,
+		httpClient: httpClient,
+		keys:       make(map[string]crypto.PublicKey),
+	}
+}
+
+func (cache *JwksCache) GetKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	age := time.Since(cache.fetchedAt)
+
+	key, ok := cache.keys[kid]
+	if ok && age < cache.ttl {
+		return key, nil
+	}
+
+	if cache.fetchedAt.IsZero() || age >= cache.ttl || age >= minJwksRefreshInterval {
+		err := cache.refresh(ctx)
+		if err != nil {
+			// Keep serving known keys while the identity provider is unreachable
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+
+		key, ok = cache.keys[kid]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("no signing key found for kid: %v", kid)
+	}
+
+	return key, nil
+}
+
+func (cache *JwksCache) refresh(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, cache.url, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create jwks request: %v", err.Error())
+	}
+
+	response, err := cache.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to fetch jwks: %v", err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to fetch jwks: status %v", response.StatusCode)
+	}
+
+	var keySet jsonWebKeySet
+	err = json.NewDecoder(response.Body).Decode(&keySet)
+	if err != nil {
+		return fmt.Errorf("unable to parse jwks: %v", err.Error())
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	cache.keys = keys
+	cache.fetchedAt = time.Now()
+
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %v", jwk.Crv)
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", jwk.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode key parameter: %v", err.Error())
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+
+	logic "cf-user/authorizer"
+)
+
+func main() {
+	if logic.Instance == nil {
+		logic.InitLambda(nil)
+	}
+
+	lambda.Start(logic.Handler)
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Request struct fields tagged `path:"name"`, `query:"name"` or `header:"name"` are populated from
+// the API Gateway request before validation runs. Path parameters are always required, as the
+// route cannot match without them, while missing query parameters and headers are left unset.
+const (
+	bindTagPath   = "path"
+	bindTagQuery  = "query"
+	bindTagHeader = "header"
+)
+
+var bindTags = []string{bindTagPath, bindTagQuery, bindTagHeader}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type BindingError struct {
+	Source string
+	Name   string
+	Field  string
+	Code   string
+	Reason string
+}
+
+func (err *BindingError) Error() string {
+	return fmt.Sprintf("%v parameter %v %v", err.Source, err.Name, err.Reason)
+}
+
+// HasBindings reports whether the given type declares any path, query or header fields.
+func HasBindings(valueType reflect.Type) bool {
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < valueType.NumField(); i++ {
+		for _, tag := range bindTags {
+			if _, ok := valueType.Field(i).Tag.Lookup(tag); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// BindRequest copies path parameters, query string parameters and headers into the tagged
+// fields of target, which must be a pointer to a struct. Every invalid field is reported.
+func BindRequest(apiRequest events.APIGatewayProxyRequest, target interface{}) []*BindingError {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return nil
+	}
+
+	value = value.Elem()
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	bindingErrors := make([]*BindingError, 0)
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, source := range bindTags {
+			name, ok := field.Tag.Lookup(source)
+			if !ok || len(name) == 0 {
+				continue
+			}
+
+			raw, found := lookupParameter(apiRequest, source, name)
+			if !found {
+				if source == bindTagPath {
+					bindingErrors = append(bindingErrors, &BindingError{Source: source, Name: name, Field: field.Name, Code: "required", Reason: "is required"})
+				}
+				continue
+			}
+
+			err := setFieldValue(value.Field(i), raw)
+			if err != nil {
+				bindingErrors = append(bindingErrors, &BindingError{Source: source, Name: name, Field: field.Name, Code: "invalid_type", Reason: err.Error()})
+			}
+		}
+	}
+
+	if len(bindingErrors) == 0 {
+		return nil
+	}
+
+	return bindingErrors
+}
+
+func lookupParameter(apiRequest events.APIGatewayProxyRequest, source string, name string) ([]string, bool) {
+	switch source {
+	case bindTagPath:
+		value, ok := apiRequest.PathParameters[name]
+		return []string{value}, ok
+	case bindTagQuery:
+		if values, ok := apiRequest.MultiValueQueryStringParameters[name]; ok && len(values) > 0 {
+			return values, true
+		}
+
+		value, ok := apiRequest.QueryStringParameters[name]
+		return []string{value}, ok
+	case bindTagHeader:
+		for key, values := range apiRequest.MultiValueHeaders {
+			if strings.EqualFold(key, name) && len(values) > 0 {
+				return values, true
+			}
+		}
+
+		value, ok := GetHeader(apiRequest.Headers, name)
+		return []string{value}, ok
+	default:
+		return nil, false
+	}
+}
+
+func setFieldValue(field reflect.Value, raw []string) error {
+	if field.Kind() == reflect.Pointer {
+		value := reflect.New(field.Type().Elem())
+		err := setFieldValue(value.Elem(), raw)
+		if err != nil {
+			return err
+		}
+
+		field.Set(value)
+		return nil
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		// Repeated parameters and comma separated values are both accepted for slices
+		items := make([]string, 0, len(raw))
+		for _, value := range raw {
+			for _, item := range strings.Split(value, ",") {
+				item = strings.TrimSpace(item)
+				if len(item) > 0 {
+					items = append(items, item)
+				}
+			}
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			err := setScalarValue(slice.Index(i), item)
+			if err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	return setScalarValue(field, raw[len(raw)-1])
+}
+
+func setScalarValue(field reflect.Value, raw string) error {
+	if field.CanAddr() {
+		if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok && field.Type() != timeType {
+			err := unmarshaler.UnmarshalText([]byte(raw))
+			if err != nil {
+				return fmt.Errorf("is invalid: %v", err.Error())
+			}
+			return nil
+		}
+	}
+
+	switch {
+	case field.Type() == timeType:
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return fmt.Errorf("must be an RFC3339 timestamp")
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	case field.Type() == durationType:
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration")
+		}
+		field.SetInt(int64(parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("has an unsupported type %v", field.Type())
+	}
+
+	return nil
+}
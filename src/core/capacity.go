@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ConsumedCapacityHeader returns the request's consumed capacity to callers outside of prod.
+const ConsumedCapacityHeader = "X-Consumed-Capacity"
+
+// Index name used for capacity consumed by the table itself.
+const tableCapacityIndex = "table"
+
+// ConsumedCapacityUsage sums capacity units. Read and write units are reported separately by
+// DynamoDB, while capacity units are their total.
+type ConsumedCapacityUsage struct {
+	Operation     string  `json:"operation,omitempty"`
+	Index         string  `json:"index,omitempty"`
+	ReadUnits     float64 `json:"readUnits"`
+	WriteUnits    float64 `json:"writeUnits"`
+	CapacityUnits float64 `json:"capacityUnits"`
+}
+
+// CapacityTracker sums the capacity consumed by the store calls of a request, by store method and index.
+type CapacityTracker struct {
+	usage map[string]*ConsumedCapacityUsage
+	mutex sync.Mutex
+}
+
+func CreateCapacityTracker() *CapacityTracker {
+	return &CapacityTracker{usage: make(map[string]*ConsumedCapacityUsage)}
+}
+
+func ContextWithCapacityTracker(ctx context.Context, tracker *CapacityTracker) context.Context {
+	return context.WithValue(ctx, capacityTrackerContextKey, tracker)
+}
+
+// CapacityTrackerFromContext returns nil when no tracker is attached, which is safe to record against.
+func CapacityTrackerFromContext(ctx context.Context) *CapacityTracker {
+	tracker, _ := ctx.Value(capacityTrackerContextKey).(*CapacityTracker)
+	return tracker
+}
+
+// Add records the capacity DynamoDB returned for a call made with ReturnConsumedCapacity INDEXES.
+func (tracker *CapacityTracker) Add(operation string, consumedCapacity *types.ConsumedCapacity) {
+	if tracker == nil || consumedCapacity == nil {
+		return
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if consumedCapacity.Table == nil && len(consumedCapacity.GlobalSecondaryIndexes) == 0 {
+		tracker.add(operation, tableCapacityIndex, types.Capacity{
+			ReadCapacityUnits:  consumedCapacity.ReadCapacityUnits,
+			WriteCapacityUnits: consumedCapacity.WriteCapacityUnits,
+			CapacityUnits:      consumedCapacity.CapacityUnits,
+		})
+		return
+	}
+
+	if consumedCapacity.Table != nil {
+		tracker.add(operation, tableCapacityIndex, *consumedCapacity.Table)
+	}
+	for index, capacity := range consumedCapacity.GlobalSecondaryIndexes {
+		tracker.add(operation, index, capacity)
+	}
+}
+
+func (tracker *CapacityTracker) add(operation string, index string, capacity types.Capacity) {
+	key := operation + "|" + index
+
+	usage, ok := tracker.usage[key]
+	if !ok {
+		usage = &ConsumedCapacityUsage{Operation: operation, Index: index}
+		tracker.usage[key] = usage
+	}
+
+	usage.ReadUnits += valueOrZero(capacity.ReadCapacityUnits)
+	usage.WriteUnits += valueOrZero(capacity.WriteCapacityUnits)
+	usage.CapacityUnits += valueOrZero(capacity.CapacityUnits)
+}
+
+// Breakdown returns the usage per store method and index, sorted by both.
+func (tracker *CapacityTracker) Breakdown() []ConsumedCapacityUsage {
+	if tracker == nil {
+		return nil
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	breakdown := make([]ConsumedCapacityUsage, 0, len(tracker.usage))
+	for _, usage := range tracker.usage {
+		breakdown = append(breakdown, *usage)
+	}
+
+	sort.Slice(breakdown, func(i, j int) bool {
+		if breakdown[i].Operation != breakdown[j].Operation {
+			return breakdown[i].Operation < breakdown[j].Operation
+		}
+		return breakdown[i].Index < breakdown[j].Index
+	})
+
+	return breakdown
+}
+
+func (tracker *CapacityTracker) Total() ConsumedCapacityUsage {
+	total := ConsumedCapacityUsage{}
+	for _, usage := range tracker.Breakdown() {
+		total.ReadUnits += usage.ReadUnits
+		total.WriteUnits += usage.WriteUnits
+		total.CapacityUnits += usage.CapacityUnits
+	}
+
+	return total
+}
+
+// HeaderValue formats the totals for the X-Consumed-Capacity header, e.g. "read=1.5, write=2, total=3.5".
+func (tracker *CapacityTracker) HeaderValue() string {
+	total := tracker.Total()
+
+	return fmt.Sprintf("read=%v, write=%v, total=%v", formatUnits(total.ReadUnits), formatUnits(total.WriteUnits), formatUnits(total.CapacityUnits))
+}
+
+// Report logs the request's consumed capacity with the requester it is attributed to, and emits it
+// as metrics by store method and index. The requesterOid is a metric property rather than a
+// dimension, as one dimension value per caller would create a metric per caller.
+func (tracker *CapacityTracker) Report(logger *slog.Logger, metrics *Metrics, requesterOid string) {
+	breakdown := tracker.Breakdown()
+	if len(breakdown) == 0 {
+		return
+	}
+
+	total := tracker.Total()
+	if logger != nil {
+		logger.Info("Consumed Capacity", "RequesterOid", requesterOid, "ReadUnits", total.ReadUnits, "WriteUnits", total.WriteUnits, "CapacityUnits", total.CapacityUnits, "Breakdown", breakdown)
+	}
+
+	if len(requesterOid) > 0 {
+		metrics.SetProperty("RequesterOid", requesterOid)
+	}
+
+	for _, usage := range breakdown {
+		dimensions := []MetricDimension{{Name: "Operation", Value: usage.Operation}, {Name: "Index", Value: usage.Index}}
+
+		metrics.AddDimensionedMetric("ConsumedReadCapacity", cfe.MetricUnitNone, usage.ReadUnits, dimensions...)
+		metrics.AddDimensionedMetric("ConsumedWriteCapacity", cfe.MetricUnitNone, usage.WriteUnits, dimensions...)
+		metrics.AddDimensionedMetric("ConsumedCapacity", cfe.MetricUnitNone, usage.CapacityUnits, dimensions...)
+	}
+}
+
+func valueOrZero(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+
+	return *value
+}
+
+func formatUnits(units float64) string {
+	return strconv.FormatFloat(units, 'f', -1, 64)
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	ETagHeader            = "ETag"
+	LastModifiedHeader    = "Last-Modified"
+	CacheControlHeader    = "Cache-Control"
+	IfNoneMatchHeader     = "If-None-Match"
+	IfModifiedSinceHeader = "If-Modified-Since"
+	AuthorizationHeader   = "Authorization"
+)
+
+// LastModifier is implemented by responses that know when their content last changed,
+// which is sent as the Last-Modified header of successful responses.
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+// CachePolicy returns the Cache-Control header for a successful response, or an empty string for none.
+type CachePolicy[TResponse interface{}] func(ctx context.Context, response *TResponse) string
+
+// ConditionalGetMiddleware tags successful GET responses with an ETag hashed from the body, and
+// answers requests whose If-None-Match or If-Modified-Since preconditions still hold with a 304.
+// If-Modified-Since is ignored when If-None-Match is present, as required by RFC 9110.
+func ConditionalGetMiddleware() Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			response := next(ctx, apiRequest)
+			if apiRequest.HTTPMethod != http.MethodGet || response.StatusCode != http.StatusOK {
+				return response
+			}
+
+			etag := ComputeETag(response.Body)
+			SetResponseHeader(&response, ETagHeader, etag)
+
+			if !IsNotModified(apiRequest.Headers, etag, response.Headers[LastModifiedHeader]) {
+				return response
+			}
+
+			MetricsFromContext(ctx).IncrementCounter("NotModified")
+
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotModified,
+				Headers:    notModifiedHeaders(response.Headers),
+			}
+		}
+	}
+}
+
+// VaryByCallerMiddleware adds Vary: Authorization to every response, including 304s, for handlers
+// whose response depends on the caller, so shared caches never serve one caller's view to another.
+func VaryByCallerMiddleware() Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			response := next(ctx, apiRequest)
+			AddVaryHeader(&response, AuthorizationHeader)
+
+			return response
+		}
+	}
+}
+
+// ComputeETag returns a strong entity tag for the response body.
+func ComputeETag(body string) string {
+	hash := sha256.Sum256([]byte(body))
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// IsNotModified evaluates the If-None-Match and If-Modified-Since request headers against the
+// current entity tag and Last-Modified value of the resource.
+func IsNotModified(headers map[string]string, etag string, lastModified string) bool {
+	if ifNoneMatch, ok := GetHeader(headers, IfNoneMatchHeader); ok {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince, ok := GetHeader(headers, IfModifiedSinceHeader)
+	if !ok || len(lastModified) == 0 {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+// FormatHttpDate formats a time as an HTTP date, which only has second precision.
+func FormatHttpDate(value time.Time) string {
+	return value.UTC().Format(http.TimeFormat)
+}
+
+// etagMatches uses the weak comparison If-None-Match calls for, so W/ prefixes are ignored.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModifiedHeaders keeps the headers a 304 must repeat from the 200 response it stands in for.
+func notModifiedHeaders(headers map[string]string) map[string]string {
+	kept := make(map[string]string)
+	for _, name := range []string{ETagHeader, LastModifiedHeader, CacheControlHeader, "Vary"} {
+		if value, ok := headers[name]; ok {
+			kept[name] = value
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthConfig holds the settings of the token authorizer, which runs without the user table and so
+// is loaded separately from Config, from the same sources.
+type AuthConfig struct {
+	Issuer   string `env:"AUTH_ISSUER" validate:"required,url"`
+	Audience string `env:"AUTH_AUDIENCE" validate:"required"`
+	// Defaults to the issuer's /.well-known/jwks.json
+	JwksUrl string        `env:"AUTH_JWKS_URL" validate:"required,url"`
+	JwksTtl time.Duration `env:"AUTH_JWKS_TTL" default:"1h" validate:"min=0"`
+	// Custom claim holding an array of permissions, merged with the scopes in the "scope" claim
+	PermissionsClaim string `env:"AUTH_PERMISSIONS_CLAIM" default:"permissions" validate:"required"`
+}
+
+var currentAuth *AuthConfig
+var authMutex sync.Mutex
+
+// CurrentAuth returns the process wide authorizer config, loading it from the default sources on first use.
+func CurrentAuth() *AuthConfig {
+	authMutex.Lock()
+	defer authMutex.Unlock()
+
+	if currentAuth == nil {
+		config, err := LoadAuth(context.TODO(), DefaultSources(context.TODO())...)
+		if err != nil {
+			log.Panicf("Unable to load auth config, %v", err.Error())
+		}
+
+		currentAuth = config
+	}
+
+	return currentAuth
+}
+
+// UseAuth replaces the process wide authorizer config, letting tests inject settings without touching the environment.
+func UseAuth(config *AuthConfig) {
+	authMutex.Lock()
+	defer authMutex.Unlock()
+
+	currentAuth = config
+}
+
+// LoadAuth merges the settings of the sources in order, binds them to an AuthConfig and validates it.
+func LoadAuth(ctx context.Context, sources ...Source) (*AuthConfig, error) {
+	config := &AuthConfig{}
+	err := loadInto(ctx, config, sources, func() {
+		if len(config.JwksUrl) == 0 && len(config.Issuer) > 0 {
+			config.JwksUrl = strings.TrimSuffix(config.Issuer, "/") + "/.well-known/jwks.json"
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
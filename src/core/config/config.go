@@ -0,0 +1,243 @@
+package config
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator"
+)
+
+// Config holds the settings of a function, loaded once at cold start. Each field is read from the
+// setting named by its `env` tag, falls back to its `default` tag and is checked by its `validate` tag.
+type Config struct {
+	Service            string     `env:"SERVICE" validate:"required"`
+	Stage              string     `env:"STAGE" validate:"required"`
+	UserTableName      string     `env:"USER_TABLE_NAME" validate:"required"`
+	MetricsNamespace   string     `env:"METRICS_NAMESPACE"`
+	LogRedactionPolicy string     `env:"LOG_REDACTION_POLICY"`
+	LogLevel           slog.Level `env:"LOG_LEVEL" default:"info"`
+	LogDebugSampleRate float64    `env:"LOG_DEBUG_SAMPLE_RATE" default:"0" validate:"min=0,max=1"`
+	CorsAllowedOrigins string     `env:"CORS_ALLOWED_ORIGINS"`
+	RateLimits         string     `env:"RATE_LIMITS"`
+	ErrorReportFile    string     `env:"ERROR_REPORT_FILE"`
+	TracesExporter     string     `env:"OTEL_TRACES_EXPORTER" validate:"omitempty,oneof=none stdout otlp"`
+	TracesEndpoint     string     `env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT" validate:"omitempty,url"`
+
+	FeatureFlagsFile      string        `env:"FEATURE_FLAGS_FILE"`
+	FeatureFlagsParameter string        `env:"FEATURE_FLAGS_PARAMETER"`
+	FeatureFlagsTtl       time.Duration `env:"FEATURE_FLAGS_TTL" default:"1m" validate:"min=0"`
+
+	ReadOnly              bool          `env:"READ_ONLY" default:"false"`
+	MaintenanceRetryAfter time.Duration `env:"MAINTENANCE_RETRY_AFTER" default:"5m" validate:"min=0"`
+}
+
+// Settings pointing at the optional sources read before the environment.
+const (
+	ConfigFileSetting          = "CONFIG_FILE"
+	ConfigParameterPathSetting = "CONFIG_PARAMETER_PATH"
+)
+
+var current *Config
+var mutex sync.Mutex
+
+// Current returns the process wide config, loading it from the default sources on first use.
+// Misconfiguration panics, so a function fails during init rather than on its first request.
+func Current() *Config {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if current == nil {
+		config, err := Load(context.TODO(), DefaultSources(context.TODO())...)
+		if err != nil {
+			log.Panicf("Unable to load config, %v", err.Error())
+		}
+
+		current = config
+	}
+
+	return current
+}
+
+// Use replaces the process wide config, letting tests inject settings without touching the environment.
+func Use(config *Config) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	current = config
+}
+
+// DefaultSources reads the JSON file named by CONFIG_FILE, then the SSM parameters under
+// CONFIG_PARAMETER_PATH, then the environment, with later sources taking precedence.
+func DefaultSources(ctx context.Context) []Source {
+	sources := make([]Source, 0)
+
+	if path := os.Getenv(ConfigFileSetting); len(path) > 0 {
+		sources = append(sources, FileSource{Path: path})
+	}
+	if path := os.Getenv(ConfigParameterPathSetting); len(path) > 0 {
+		sources = append(sources, &SsmSource{Path: path})
+	}
+
+	return append(sources, EnvSource{})
+}
+
+// Load merges the settings of the sources in order, binds them to a Config and validates it.
+func Load(ctx context.Context, sources ...Source) (*Config, error) {
+	config := &Config{}
+	err := loadInto(ctx, config, sources, func() {
+		if len(config.MetricsNamespace) == 0 {
+			config.MetricsNamespace = config.Service
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate checks the `validate` tags, reporting fields by their setting names.
+func (config *Config) Validate() error {
+	return validateSettings(config)
+}
+
+// loadInto merges the settings of the sources in order and binds them to the target struct,
+// applying the derived defaults before the target is validated.
+func loadInto(ctx context.Context, target interface{}, sources []Source, applyDefaults func()) error {
+	settings := make(map[string]string)
+
+	for _, source := range sources {
+		values, err := source.Values(ctx)
+		if err != nil {
+			return err
+		}
+
+		for name, value := range values {
+			settings[name] = value
+		}
+	}
+
+	err := bind(reflect.ValueOf(target).Elem(), settings)
+	if err != nil {
+		return err
+	}
+
+	applyDefaults()
+
+	return validateSettings(target)
+}
+
+func validateSettings(target interface{}) error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("env")
+	})
+
+	err := validate.Struct(target)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return fmt.Errorf("unable to validate config: %v", err.Error())
+	}
+
+	messages := make([]string, 0)
+	for _, fieldError := range validationErrors {
+		messages = append(messages, fmt.Sprintf("%v failed %v", fieldError.Field(), fieldError.Tag()))
+	}
+
+	return fmt.Errorf("invalid config: %v", strings.Join(messages, ", "))
+}
+
+func bind(target reflect.Value, settings map[string]string) error {
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+
+		name := field.Tag.Get("env")
+		if len(name) == 0 {
+			continue
+		}
+
+		value, ok := settings[name]
+		if !ok || len(value) == 0 {
+			value, ok = field.Tag.Lookup("default")
+		}
+		if !ok {
+			continue
+		}
+
+		err := parseSetting(target.Field(i), strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid config setting %v: %v", name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func parseSetting(field reflect.Value, value string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", field.Type())
+		}
+
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Source provides settings keyed by their environment variable names.
+type Source interface {
+	Values(ctx context.Context) (map[string]string, error)
+}
+
+// MapSource provides fixed settings, e.g. for tests.
+type MapSource map[string]string
+
+func (source MapSource) Values(ctx context.Context) (map[string]string, error) {
+	return source, nil
+}
+
+// EnvSource provides the process environment.
+type EnvSource struct{}
+
+func (source EnvSource) Values(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// FileSource reads a JSON object of settings, e.g. {"USER_TABLE_NAME": "cf-user-dev-app-user"}.
+type FileSource struct {
+	Path string
+}
+
+func (source FileSource) Values(ctx context.Context) (map[string]string, error) {
+	content, err := os.ReadFile(source.Path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %v", err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var document map[string]interface{}
+	err = decoder.Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config file: %v", err.Error())
+	}
+
+	values := make(map[string]string)
+	for name, value := range document {
+		switch typed := value.(type) {
+		case nil:
+			continue
+		case []interface{}:
+			items := make([]string, 0)
+			for _, item := range typed {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]interface{}:
+			// Nested settings such as RATE_LIMITS are kept as JSON
+			nested, _ := json.Marshal(typed)
+			values[name] = string(nested)
+		default:
+			values[name] = fmt.Sprint(typed)
+		}
+	}
+
+	return values, nil
+}
+
+// SsmSource reads the parameters directly under a path, e.g. /cf-user/dev/config/USER_TABLE_NAME,
+// decrypting secure strings. The client is created from the default AWS config when not set.
+type SsmSource struct {
+	Path   string
+	Client ssm.GetParametersByPathAPIClient
+}
+
+func (source *SsmSource) Values(ctx context.Context) (map[string]string, error) {
+	if source.Client == nil {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %v", err.Error())
+		}
+
+		source.Client = ssm.NewFromConfig(cfg)
+	}
+
+	withDecryption := true
+	paginator := ssm.NewGetParametersByPathPaginator(source.Client, &ssm.GetParametersByPathInput{
+		Path:           &source.Path,
+		WithDecryption: &withDecryption,
+	})
+
+	values := make(map[string]string)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch config parameters: %v", err.Error())
+		}
+
+		for _, parameter := range page.Parameters {
+			if parameter.Name == nil || parameter.Value == nil {
+				continue
+			}
+
+			values[path.Base(*parameter.Name)] = *parameter.Value
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,14 @@
+package core
+
+type contextKey int
+
+const (
+	metricsContextKey contextKey = iota
+	correlationIdContextKey
+	requesterOidContextKey
+	errorReporterContextKey
+	errorRequestContextKey
+	dryRunContextKey
+	redactorContextKey
+	capacityTrackerContextKey
+)
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/oklog/ulid/v2"
+)
+
+const CorrelationIdHeader = "X-Correlation-Id"
+
+// Incoming ids are echoed into logs and headers, so only a conservative character set is accepted.
+var correlationIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// ResolveCorrelationId reuses the caller supplied X-Correlation-Id, or generates a new ULID.
+func ResolveCorrelationId(apiRequest events.APIGatewayProxyRequest) string {
+	correlationId, ok := GetHeader(apiRequest.Headers, CorrelationIdHeader)
+	if ok && correlationIdPattern.MatchString(correlationId) {
+		return correlationId
+	}
+
+	return ulid.Make().String()
+}
+
+func ContextWithCorrelationId(ctx context.Context, correlationId string) context.Context {
+	return context.WithValue(ctx, correlationIdContextKey, correlationId)
+}
+
+func CorrelationIdFromContext(ctx context.Context) string {
+	correlationId, _ := ctx.Value(correlationIdContextKey).(string)
+	return correlationId
+}
+
+// CorrelationTransport forwards the correlation id of the request context on outbound HTTP calls,
+// e.g. http.Client{Transport: &CorrelationTransport{}}.
+type CorrelationTransport struct {
+	Next http.RoundTripper
+}
+
+func (transport *CorrelationTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	next := transport.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	correlationId := CorrelationIdFromContext(request.Context())
+	if len(correlationId) == 0 || len(request.Header.Get(CorrelationIdHeader)) > 0 {
+		return next.RoundTrip(request)
+	}
+
+	outbound := request.Clone(request.Context())
+	outbound.Header.Set(CorrelationIdHeader, correlationId)
+
+	return next.RoundTrip(outbound)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	OriginHeader                        = "Origin"
+	AccessControlAllowOriginHeader      = "Access-Control-Allow-Origin"
+	AccessControlAllowCredentialsHeader = "Access-Control-Allow-Credentials"
+	AccessControlExposeHeadersHeader    = "Access-Control-Expose-Headers"
+	AccessControlMaxAgeHeader           = "Access-Control-Max-Age"
+)
+
+const defaultCorsMaxAge = 10 * time.Minute
+
+// Origins allowed when CORS_ALLOWED_ORIGINS is not set. Stages other than prod also allow local development.
+var defaultCorsOrigins = map[string][]string{
+	"prod": {"https://classifind.app", "https://*.classifind.app"},
+}
+
+var developmentCorsOrigins = []string{"https://classifind.app", "https://*.classifind.app", "http://localhost:3000"}
+
+// Response headers browsers may read, in addition to the CORS safelisted ones.
+var defaultCorsExposedHeaders = []string{
+	CorrelationIdHeader,
+	ETagHeader,
+	LastModifiedHeader,
+	"Retry-After",
+	RateLimitLimitHeader,
+	RateLimitRemainingHeader,
+	RateLimitResetHeader,
+	IdempotentReplayedHeader,
+	ConsumedCapacityHeader,
+}
+
+// CorsPolicy decides which browser origins may read responses. An allowed origin is an exact
+// origin such as "https://classifind.app", a wildcard subdomain such as "https://*.classifind.app",
+// or "*" for any origin. The matching origin is echoed back, so credentialed requests work with
+// subdomain wildcards. "*" is sent as is and never allows credentials, so any site may read
+// public responses but not make credentialed requests.
+type CorsPolicy struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	ExposedHeaders   []string
+	MaxAge           time.Duration
+}
+
+// GetCorsPolicy returns the policy for the stage, with the origins overridden by the comma
+// separated CORS_ALLOWED_ORIGINS setting when present.
+func GetCorsPolicy(stage string, allowedOrigins string) (*CorsPolicy, error) {
+	origins, ok := defaultCorsOrigins[stage]
+	if !ok {
+		origins = developmentCorsOrigins
+	}
+
+	if len(strings.TrimSpace(allowedOrigins)) > 0 {
+		origins = make([]string, 0)
+		for _, origin := range strings.Split(allowedOrigins, ",") {
+			origin = strings.TrimSpace(origin)
+			if len(origin) == 0 {
+				continue
+			}
+			if origin != "*" && !strings.Contains(origin, "://") {
+				return nil, fmt.Errorf("invalid cors origin, expected scheme://host: %v", origin)
+			}
+
+			origins = append(origins, strings.ToLower(strings.TrimSuffix(origin, "/")))
+		}
+	}
+
+	return &CorsPolicy{
+		AllowedOrigins:   origins,
+		AllowCredentials: !slices.Contains(origins, "*"),
+		ExposedHeaders:   defaultCorsExposedHeaders,
+		MaxAge:           defaultCorsMaxAge,
+	}, nil
+}
+
+func (policy CorsPolicy) AllowsOrigin(origin string) bool {
+	if len(origin) == 0 || origin == "null" {
+		return false
+	}
+
+	origin = strings.ToLower(origin)
+
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+
+		// "https://*.example.com" allows any subdomain of example.com, but not example.com itself
+		subdomain, found := strings.CutPrefix(origin, scheme+"://")
+		if found && strings.HasSuffix(subdomain, "."+host) && len(subdomain) > len(host)+1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply adds the CORS headers for the request origin to the response. Responses always vary by
+// Origin, so caches do not serve one origin's headers to another.
+func (policy CorsPolicy) Apply(apiRequest events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse) {
+	AddVaryHeader(response, OriginHeader)
+
+	origin, _ := GetHeader(apiRequest.Headers, OriginHeader)
+	if !policy.AllowsOrigin(origin) {
+		return
+	}
+
+	if slices.Contains(policy.AllowedOrigins, "*") {
+		SetResponseHeader(response, AccessControlAllowOriginHeader, "*")
+	} else {
+		SetResponseHeader(response, AccessControlAllowOriginHeader, origin)
+	}
+
+	if policy.AllowCredentials && !slices.Contains(policy.AllowedOrigins, "*") {
+		SetResponseHeader(response, AccessControlAllowCredentialsHeader, "true")
+	}
+	if len(policy.ExposedHeaders) > 0 {
+		SetResponseHeader(response, AccessControlExposeHeadersHeader, strings.Join(policy.ExposedHeaders, ", "))
+	}
+	if policy.MaxAge > 0 {
+		SetResponseHeader(response, AccessControlMaxAgeHeader, fmt.Sprint(int(policy.MaxAge.Seconds())))
+	}
+}
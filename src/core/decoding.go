@@ -0,0 +1,383 @@
+package core
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const DefaultMaxBodyBytes = 64 * 1024
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// DecodeOptions controls how request bodies are decoded. The zero value keeps the lenient
+// behaviour of json.Unmarshal, where unknown fields are ignored and the last duplicate key wins.
+type DecodeOptions struct {
+	DisallowUnknownFields bool
+	DisallowDuplicateKeys bool
+	MaxBodyBytes          int
+}
+
+// StrictDecodeOptions rejects anything the request model does not declare, so a client is
+// never told an update succeeded when part of the payload was silently ignored.
+func StrictDecodeOptions() DecodeOptions {
+	return DecodeOptions{
+		DisallowUnknownFields: true,
+		DisallowDuplicateKeys: true,
+		MaxBodyBytes:          DefaultMaxBodyBytes,
+	}
+}
+
+type DecodingError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func (err *DecodingError) Error() string {
+	if len(err.Field) == 0 {
+		return err.Message
+	}
+
+	return fmt.Sprintf("%v: %v", err.Field, err.Message)
+}
+
+// ReadRequestBody returns the raw request body, decoding it first when API Gateway passed it base64 encoded.
+func ReadRequestBody(apiRequest events.APIGatewayProxyRequest, options DecodeOptions) ([]byte, *cfe.ResponseError) {
+	body := []byte(apiRequest.Body)
+
+	if apiRequest.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(apiRequest.Body)
+		if err != nil {
+			e := cfe.ErrorValidation("Body is not valid base64.")
+			return nil, &e
+		}
+
+		body = decoded
+	}
+
+	if options.MaxBodyBytes > 0 && len(body) > options.MaxBodyBytes {
+		e := cfe.ErrorPayloadTooLarge(fmt.Sprintf("Body must not exceed %v bytes.", options.MaxBodyBytes))
+		return nil, &e
+	}
+
+	return bytes.TrimSpace(body), nil
+}
+
+// DecodeJson decodes a single JSON value into target, reporting the offending field when possible.
+func DecodeJson(body []byte, options DecodeOptions, target interface{}) *DecodingError {
+	if options.DisallowDuplicateKeys {
+		err := findDuplicateKey(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if options.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	err := decoder.Decode(target)
+	if err != nil {
+		decodingError := toDecodingError(err)
+		if decodingError.Code == "unknown_field" {
+			// The decoder only names the unknown key, so walk the body for its full path
+			if unknown := findUnknownField(body, reflect.TypeOf(target)); unknown != nil {
+				return unknown
+			}
+		}
+
+		return decodingError
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return &DecodingError{Code: "trailing_data", Message: "body must contain a single JSON value"}
+	}
+
+	return nil
+}
+
+func toDecodingError(err error) *DecodingError {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return &DecodingError{Code: "invalid_json", Message: fmt.Sprintf("malformed JSON at offset %v", syntaxError.Offset)}
+	case errors.As(err, &typeError):
+		return &DecodingError{Field: typeError.Field, Code: "invalid_type", Message: fmt.Sprintf("must be of type %v", jsonTypeName(typeError.Type))}
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodingError{Code: "invalid_json", Message: "unexpected end of JSON input"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return &DecodingError{Field: field, Code: "unknown_field", Message: "is not a recognised field"}
+	default:
+		return &DecodingError{Code: "invalid_json", Message: err.Error()}
+	}
+}
+
+// findDuplicateKey walks the JSON tokens and reports the path of the first repeated object key.
+func findDuplicateKey(body []byte) *DecodingError {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	return walkJsonValue(decoder, "")
+}
+
+func walkJsonValue(decoder *json.Decoder, path string) *DecodingError {
+	token, err := decoder.Token()
+	if err != nil {
+		// Malformed input is reported by the decoder itself
+		return nil
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		keys := make(map[string]bool)
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil
+			}
+
+			key, _ := keyToken.(string)
+			keyPath := joinJsonPath(path, key)
+			if keys[key] {
+				return &DecodingError{Field: keyPath, Code: "duplicate_key", Message: "is specified more than once"}
+			}
+			keys[key] = true
+
+			if err := walkJsonValue(decoder, keyPath); err != nil {
+				return err
+			}
+		}
+		decoder.Token()
+	case '[':
+		for i := 0; decoder.More(); i++ {
+			if err := walkJsonValue(decoder, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+		decoder.Token()
+	}
+
+	return nil
+}
+
+// findUnknownField walks the JSON tokens alongside the target type and reports the path of the first
+// object key that does not match a field.
+func findUnknownField(body []byte, targetType reflect.Type) *DecodingError {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	return walkJsonFields(decoder, targetType, "")
+}
+
+func walkJsonFields(decoder *json.Decoder, valueType reflect.Type, path string) *DecodingError {
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	switch valueType.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if !isUnmarshaler(valueType) {
+			break
+		}
+		fallthrough
+	default:
+		// Values decoded as a whole cannot hold unknown fields
+		var skipped json.RawMessage
+		decoder.Decode(&skipped)
+		return nil
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil
+			}
+
+			key, _ := keyToken.(string)
+			keyPath := joinJsonPath(path, key)
+
+			var elemType reflect.Type
+			switch valueType.Kind() {
+			case reflect.Struct:
+				fieldType, ok := jsonFieldType(valueType, key)
+				if !ok {
+					return &DecodingError{Field: keyPath, Code: "unknown_field", Message: "is not a recognised field"}
+				}
+				elemType = fieldType
+			case reflect.Map:
+				elemType = valueType.Elem()
+			default:
+				elemType = reflect.TypeOf(json.RawMessage{})
+			}
+
+			if err := walkJsonFields(decoder, elemType, keyPath); err != nil {
+				return err
+			}
+		}
+		decoder.Token()
+	case '[':
+		elemType := reflect.TypeOf(json.RawMessage{})
+		if valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
+			elemType = valueType.Elem()
+		}
+
+		for i := 0; decoder.More(); i++ {
+			if err := walkJsonFields(decoder, elemType, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+		decoder.Token()
+	}
+
+	return nil
+}
+
+// jsonFieldType returns the type of the struct field a JSON key decodes into, matching names
+// case-insensitively and looking through embedded structs as encoding/json does.
+func jsonFieldType(structType reflect.Type, key string) (reflect.Type, bool) {
+	var folded reflect.Type
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, _, skip := jsonFieldName(field)
+		if skip || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && len(name) == 0 && fieldType.Kind() == reflect.Struct {
+			if embeddedType, ok := jsonFieldType(fieldType, key); ok {
+				return embeddedType, true
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		if name == key {
+			return field.Type, true
+		}
+		if folded == nil && strings.EqualFold(name, key) {
+			folded = field.Type
+		}
+	}
+
+	return folded, folded != nil
+}
+
+func isUnmarshaler(valueType reflect.Type) bool {
+	pointerType := reflect.PointerTo(valueType)
+	return pointerType.Implements(jsonUnmarshalerType) || pointerType.Implements(textUnmarshalerType)
+}
+
+// jsonFieldPath converts a validator namespace such as "UpdateUserRequest.PrimaryAddress.PostalCode"
+// into the JSON path the client sent, e.g. "primaryAddress.postalCode".
+func jsonFieldPath(rootType reflect.Type, structNamespace string) string {
+	segments := strings.Split(structNamespace, ".")
+	if len(segments) > 1 {
+		segments = segments[1:]
+	}
+
+	path := ""
+	currentType := rootType
+
+	for _, segment := range segments {
+		name, index, _ := strings.Cut(segment, "[")
+		if len(index) > 0 {
+			index = "[" + index
+		}
+
+		for currentType != nil && (currentType.Kind() == reflect.Pointer || currentType.Kind() == reflect.Slice || currentType.Kind() == reflect.Array || currentType.Kind() == reflect.Map) {
+			currentType = currentType.Elem()
+		}
+
+		jsonName := name
+		if currentType != nil && currentType.Kind() == reflect.Struct {
+			if field, ok := currentType.FieldByName(name); ok {
+				if tagName, _, skip := jsonFieldName(field); !skip && len(tagName) > 0 {
+					jsonName = tagName
+				}
+				currentType = field.Type
+			} else {
+				currentType = nil
+			}
+		}
+
+		path = joinJsonPath(path, jsonName) + index
+	}
+
+	return path
+}
+
+func joinJsonPath(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func jsonTypeName(valueType reflect.Type) string {
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	switch valueType.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
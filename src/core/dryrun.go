@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const PreferHeader = "Prefer"
+const PreferenceAppliedHeader = "Preference-Applied"
+
+const dryRunPreference = "dry-run"
+const dryRunQueryParameter = "dryRun"
+
+// IsDryRun reports whether the request asks for a write to be checked without persisting it,
+// using a "Prefer: dry-run" header or a dryRun=true query parameter.
+func IsDryRun(apiRequest events.APIGatewayProxyRequest) bool {
+	if prefer, ok := GetHeader(apiRequest.Headers, PreferHeader); ok {
+		for _, preference := range strings.Split(prefer, ",") {
+			name, _, _ := strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(name), dryRunPreference) {
+				return true
+			}
+		}
+	}
+
+	dryRun, err := strconv.ParseBool(apiRequest.QueryStringParameters[dryRunQueryParameter])
+	return err == nil && dryRun
+}
+
+func ContextWithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunContextKey, dryRun)
+}
+
+func DryRunFromContext(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunContextKey).(bool)
+	return dryRun
+}
+
+// DryRunMiddleware marks dry runs in the request context, where the store replaces writes with
+// checks of their conditions, and acknowledges them with a Preference-Applied header. It must be
+// registered before IdempotencyMiddleware, which does not record dry runs.
+func DryRunMiddleware() Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			if !IsDryRun(apiRequest) {
+				return next(ctx, apiRequest)
+			}
+
+			MetricsFromContext(ctx).IncrementCounter("DryRuns")
+
+			response := next(ContextWithDryRun(ctx, true), apiRequest)
+			SetResponseHeader(&response, PreferenceAppliedHeader, dryRunPreference)
+
+			return response
+		}
+	}
+}
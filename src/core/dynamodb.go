@@ -4,11 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
 	"strings"
 	"time"
 
+	cfconfig "cf-user/core/config"
 	cfe "cf-user/core/enums"
 	cfm "cf-user/core/models"
 
@@ -16,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DynamoDbStore struct {
@@ -25,29 +26,48 @@ type DynamoDbStore struct {
 
 var gsi1IndexName = "GSI1"
 var gsi2IndexName = "GSI2"
+var gsi3IndexName = "GSI3"
 
-func CreateDynamoDbStore(dynamoDb *dynamodb.Client) *DynamoDbStore {
-	tableName := os.Getenv("USER_TABLE_NAME")
+const usernameClaimPrefix = "USERNAME"
+const subjectClaimPrefix = "SUBJECT"
 
+func CreateDynamoDbStore(dynamoDb *dynamodb.Client) *DynamoDbStore {
 	return &DynamoDbStore{
 		dynamoDb:  dynamoDb,
-		tableName: tableName,
+		tableName: cfconfig.Current().UserTableName,
 	}
 }
 
 type DynamoDb interface {
-	WipeTestData() error
-
-	GetUser(userId string) (*cfm.User, error)
-	GetUserByUsername(username string) (*cfm.User, error)
-	GetUserByEmail(emailAddress string) (*cfm.User, error)
-	CreateUser(group *cfm.User) (*string, error)
-	UpdateUser(userId string, group *cfm.User) (*bool, error)
-	DeleteUser(userId string) (*bool, error)
+	WipeTestData(ctx context.Context) error
+
+	GetUser(ctx context.Context, userId string, attributes ...string) (*cfm.User, error)
+	GetUserByUsername(ctx context.Context, username string, attributes ...string) (*cfm.User, error)
+	GetUserByEmail(ctx context.Context, emailAddress string, attributes ...string) (*cfm.User, error)
+	GetUserByIdentitySubject(ctx context.Context, subject string, attributes ...string) (*cfm.User, error)
+	CreateUser(ctx context.Context, group *cfm.User) (*string, error)
+	UpdateUser(ctx context.Context, userId string, group *cfm.User) (*bool, error)
+	RenameUser(ctx context.Context, userId string, previousUsername string, group *cfm.User) (*bool, error)
+	LinkIdentitySubject(ctx context.Context, userId string, previousSubject *string, subject string) (*bool, error)
+	DeleteUser(ctx context.Context, userId string) (*bool, error)
+
+	StartIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord) (*cfm.IdempotencyRecord, error)
+	CompleteIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord) error
+	DeleteIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord) error
+
+	GetRateLimitBucket(ctx context.Context, pk string, sk string) (*cfm.RateLimitBucket, error)
+	SaveRateLimitBucket(ctx context.Context, bucket *cfm.RateLimitBucket, previousUpdatedAt *int64) (*bool, error)
+
+	DescribeUserTable(ctx context.Context) (map[string]string, error)
+	ProbeUserTable(ctx context.Context) error
+	ProbeUserIndex(ctx context.Context, indexName string) error
 }
 
-func (DynamoDbStore *DynamoDbStore) WipeTestData() error {
-	stage := os.Getenv("STAGE")
+func (DynamoDbStore *DynamoDbStore) WipeTestData(ctx context.Context) error {
+	ctx, span := DynamoDbStore.startSpan(ctx, "WipeTestData", "Scan", nil)
+	defer span.End()
+
+	stage := cfconfig.Current().Stage
 	if stage == "prod" || stage == "stage" {
 		return errors.New("cannot delete data in prod or staging")
 	}
@@ -57,18 +77,23 @@ func (DynamoDbStore *DynamoDbStore) WipeTestData() error {
 	filterExpression := "contains(EmailAddress, :email_address_domain)"
 
 	scanInput := &dynamodb.ScanInput{
-		TableName:        &DynamoDbStore.tableName,
-		FilterExpression: &filterExpression,
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		FilterExpression:       &filterExpression,
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":email_address_domain": canaryDomain,
 		},
 	}
 
-	page, err := DynamoDbStore.dynamoDb.Scan(context.TODO(), scanInput)
+	callStart := time.Now()
+	page, err := DynamoDbStore.dynamoDb.Scan(ctx, scanInput)
+	DynamoDbStore.observeCall(ctx, "Scan", callStart, scanInput, page, err)
 	if err != nil {
 		return fmt.Errorf("unable to fetch canary users: %v", err.Error())
 	}
 
+	recordConsumedCapacity(ctx, "WipeTestData", page.ConsumedCapacity)
+
 	users := []cfm.User{}
 	err = attributevalue.UnmarshalListOfMaps(page.Items, &users)
 	if err != nil {
@@ -76,28 +101,41 @@ func (DynamoDbStore *DynamoDbStore) WipeTestData() error {
 	}
 
 	for _, user := range users {
-		DynamoDbStore.DeleteUser(user.UserId)
+		DynamoDbStore.DeleteUser(ctx, user.UserId)
 	}
 
 	return nil
 }
 
-func (DynamoDbStore *DynamoDbStore) GetUser(userId string) (*cfm.User, error) {
+func (DynamoDbStore *DynamoDbStore) GetUser(ctx context.Context, userId string, attributes ...string) (*cfm.User, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "GetUser", "GetItem", nil)
+	defer span.End()
+
 	pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
 	skAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
 
+	projection, projectionNames := projectionExpression(attributes)
+
 	queryInput := &dynamodb.GetItemInput{
-		TableName: &DynamoDbStore.tableName,
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
 		Key: map[string]types.AttributeValue{
 			"PK": pkAttribute,
 			"SK": skAttribute,
 		},
+		ProjectionExpression:     projection,
+		ExpressionAttributeNames: projectionNames,
 	}
 
-	response, err := DynamoDbStore.dynamoDb.GetItem(context.TODO(), queryInput)
+	callStart := time.Now()
+	response, err := DynamoDbStore.dynamoDb.GetItem(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "GetItem", callStart, queryInput, response, err)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch user: %v", err.Error())
 	}
+
+	recordConsumedCapacity(ctx, "GetUser", response.ConsumedCapacity)
+
 	if response.Item == nil {
 		return nil, cfe.ErrorNotFound()
 	}
@@ -111,27 +149,38 @@ func (DynamoDbStore *DynamoDbStore) GetUser(userId string) (*cfm.User, error) {
 	return &user, nil
 }
 
-func (DynamoDbStore *DynamoDbStore) GetUserByUsername(username string) (*cfm.User, error) {
+func (DynamoDbStore *DynamoDbStore) GetUserByUsername(ctx context.Context, username string, attributes ...string) (*cfm.User, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "GetUserByUsername", "Query", &gsi1IndexName)
+	defer span.End()
+
 	gsi1pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USERNAME#%v", username))
 	gsi1skAttribute, _ := attributevalue.Marshal("USER#")
 
 	keyCondition := "GSI1PK = :gsi1pk and begins_with(GSI1SK, :gsi1sk)"
+	projection, projectionNames := projectionExpression(attributes)
 
 	queryInput := &dynamodb.QueryInput{
 		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
 		IndexName:              &gsi1IndexName,
 		KeyConditionExpression: &keyCondition,
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":gsi1pk": gsi1pkAttribute,
 			":gsi1sk": gsi1skAttribute,
 		},
+		ProjectionExpression:     projection,
+		ExpressionAttributeNames: projectionNames,
 	}
 
-	page, err := DynamoDbStore.dynamoDb.Query(context.TODO(), queryInput)
+	callStart := time.Now()
+	page, err := DynamoDbStore.dynamoDb.Query(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "Query", callStart, queryInput, page, err)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch user(s) by username: %v", err.Error())
 	}
 
+	recordConsumedCapacity(ctx, "GetUserByUsername", page.ConsumedCapacity)
+
 	users := []cfm.User{}
 	err = attributevalue.UnmarshalListOfMaps(page.Items, &users)
 	if err != nil {
@@ -139,33 +188,44 @@ func (DynamoDbStore *DynamoDbStore) GetUserByUsername(username string) (*cfm.Use
 	}
 
 	if len(users) < 1 {
-		return nil, fmt.Errorf("no user found with given username: %v", username)
+		return nil, cfe.ErrorNotFound()
 	}
 
 	return &users[0], nil
 }
 
-func (DynamoDbStore *DynamoDbStore) GetUserByEmail(emailAddress string) (*cfm.User, error) {
+func (DynamoDbStore *DynamoDbStore) GetUserByEmail(ctx context.Context, emailAddress string, attributes ...string) (*cfm.User, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "GetUserByEmail", "Query", &gsi2IndexName)
+	defer span.End()
+
 	gsi2pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("EMAIL_ADDRESS#%v", emailAddress))
 	gsi2skAttribute, _ := attributevalue.Marshal("USER#")
 
 	keyCondition := "GSI2PK = :gsi2pk and begins_with(GSI2SK, :gsi2sk)"
+	projection, projectionNames := projectionExpression(attributes)
 
 	queryInput := &dynamodb.QueryInput{
 		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
 		IndexName:              &gsi2IndexName,
 		KeyConditionExpression: &keyCondition,
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":gsi2pk": gsi2pkAttribute,
 			":gsi2sk": gsi2skAttribute,
 		},
+		ProjectionExpression:     projection,
+		ExpressionAttributeNames: projectionNames,
 	}
 
-	page, err := DynamoDbStore.dynamoDb.Query(context.TODO(), queryInput)
+	callStart := time.Now()
+	page, err := DynamoDbStore.dynamoDb.Query(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "Query", callStart, queryInput, page, err)
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch user(s) by email address: %v", err.Error())
 	}
 
+	recordConsumedCapacity(ctx, "GetUserByEmail", page.ConsumedCapacity)
+
 	users := []cfm.User{}
 	err = attributevalue.UnmarshalListOfMaps(page.Items, &users)
 	if err != nil {
@@ -173,13 +233,61 @@ func (DynamoDbStore *DynamoDbStore) GetUserByEmail(emailAddress string) (*cfm.Us
 	}
 
 	if len(users) < 1 {
-		return nil, fmt.Errorf("no user found with given email address: %v", emailAddress)
+		return nil, cfe.ErrorNotFound()
 	}
 
 	return &users[0], nil
 }
 
-func (DynamoDbStore *DynamoDbStore) CreateUser(user *cfm.User) (*string, error) {
+func (DynamoDbStore *DynamoDbStore) GetUserByIdentitySubject(ctx context.Context, subject string, attributes ...string) (*cfm.User, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "GetUserByIdentitySubject", "Query", &gsi3IndexName)
+	defer span.End()
+
+	gsi3pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("SUBJECT#%v", subject))
+	gsi3skAttribute, _ := attributevalue.Marshal("USER#")
+
+	keyCondition := "GSI3PK = :gsi3pk and begins_with(GSI3SK, :gsi3sk)"
+	projection, projectionNames := projectionExpression(attributes)
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		IndexName:              &gsi3IndexName,
+		KeyConditionExpression: &keyCondition,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":gsi3pk": gsi3pkAttribute,
+			":gsi3sk": gsi3skAttribute,
+		},
+		ProjectionExpression:     projection,
+		ExpressionAttributeNames: projectionNames,
+	}
+
+	callStart := time.Now()
+	page, err := DynamoDbStore.dynamoDb.Query(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "Query", callStart, queryInput, page, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch user(s) by identity subject: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "GetUserByIdentitySubject", page.ConsumedCapacity)
+
+	users := []cfm.User{}
+	err = attributevalue.UnmarshalListOfMaps(page.Items, &users)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse users from page: %v", err)
+	}
+
+	if len(users) < 1 {
+		return nil, cfe.ErrorNotFound()
+	}
+
+	return &users[0], nil
+}
+
+func (DynamoDbStore *DynamoDbStore) CreateUser(ctx context.Context, user *cfm.User) (*string, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "CreateUser", "TransactWriteItems", nil)
+	defer span.End()
+
 	now := time.Now().UTC()
 	userId := ulid.Make().String()
 
@@ -200,48 +308,860 @@ func (DynamoDbStore *DynamoDbStore) CreateUser(user *cfm.User) (*string, error)
 	user.GSI2PK = &gsi2pk
 	user.GSI2SK = &gsi2sk
 	user.CreatedDate = now
+
+	// Only users linked to an identity are written to the sparse subject index
+	if user.IdentitySubject != nil {
+		gsi3pk := fmt.Sprintf("SUBJECT#%v", *user.IdentitySubject)
+		gsi3sk := fmt.Sprintf("USER#%v", userId)
+
+		user.GSI3PK = &gsi3pk
+		user.GSI3SK = &gsi3sk
+	}
 	user.UpdatedDate = now
 
 	conditionExpression := "PK <> :pk"
 
+	// The key is a new ULID, so there is no condition worth checking in a dry run. Email, username and
+	// identity subject uniqueness are checked by the handler before the store is called.
+	if DryRunFromContext(ctx) {
+		return &user.UserId, nil
+	}
+
 	item, err := attributevalue.MarshalMap(user)
 	if err != nil {
 		return nil, fmt.Errorf("unable to convert User to Attribute Value map: %v", err.Error())
 	}
 
-	putInput := &dynamodb.PutItemInput{
-		TableName:           &DynamoDbStore.tableName,
-		Item:                item,
-		ConditionExpression: &conditionExpression,
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": pkAttribute,
+	claimItem, err := attributevalue.MarshalMap(uniqueClaim(usernameClaimPrefix, user.Username, userId))
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert UniqueClaim to Attribute Value map: %v", err.Error())
+	}
+
+	claimCondition := "attribute_not_exists(PK)"
+
+	transactInput := &dynamodb.TransactWriteItemsInput{
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           &DynamoDbStore.tableName,
+					Item:                item,
+					ConditionExpression: &conditionExpression,
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":pk": pkAttribute,
+					},
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           &DynamoDbStore.tableName,
+					Item:                claimItem,
+					ConditionExpression: &claimCondition,
+				},
+			},
 		},
 	}
 
-	_, err = DynamoDbStore.dynamoDb.PutItem(context.TODO(), putInput)
+	if user.IdentitySubject != nil {
+		subjectClaimItem, err := attributevalue.MarshalMap(uniqueClaim(subjectClaimPrefix, *user.IdentitySubject, userId))
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert UniqueClaim to Attribute Value map: %v", err.Error())
+		}
+
+		transactInput.TransactItems = append(transactInput.TransactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           &DynamoDbStore.tableName,
+				Item:                subjectClaimItem,
+				ConditionExpression: &claimCondition,
+			},
+		})
+	}
+
+	callStart := time.Now()
+	transactOutput, err := DynamoDbStore.dynamoDb.TransactWriteItems(ctx, transactInput)
+	if failedCondition(err, 1) != nil {
+		DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+		return nil, cfe.ErrorValidation(fmt.Sprintf("User already exists with given username: %v", user.Username))
+	}
+	if failedCondition(err, 2) != nil {
+		DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+		return nil, cfe.ErrorValidation("User already exists for the given identity subject.")
+	}
+
+	DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, err)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create user: %v", err.Error())
 	}
 
+	for _, consumedCapacity := range transactOutput.ConsumedCapacity {
+		recordConsumedCapacity(ctx, "CreateUser", &consumedCapacity)
+	}
+
 	return &user.UserId, nil
 }
 
-func (DynamoDbStore *DynamoDbStore) UpdateUser(userId string, group *cfm.User) (*bool, error) {
+func (DynamoDbStore *DynamoDbStore) UpdateUser(ctx context.Context, userId string, group *cfm.User) (*bool, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "UpdateUser", "UpdateItem", nil)
+	defer span.End()
+
+	update, err := DynamoDbStore.userUpdate(userId, group)
+	if err != nil {
+		return nil, err
+	}
+
+	if DryRunFromContext(ctx) {
+		passed, err := DynamoDbStore.checkCondition(ctx, "UpdateUser", update.Key["PK"], update.Key["SK"], *update.ConditionExpression, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !passed {
+			return nil, cfe.ErrorNotFound()
+		}
+
+		success := true
+		return &success, nil
+	}
+
+	updateInput := &dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+		Key:                       update.Key,
+		ConditionExpression:       update.ConditionExpression,
+		UpdateExpression:          update.UpdateExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+	}
+
+	callStart := time.Now()
+	updateOutput, err := DynamoDbStore.dynamoDb.UpdateItem(ctx, updateInput)
+	DynamoDbStore.observeCall(ctx, "UpdateItem", callStart, updateInput, updateOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update user: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "UpdateUser", updateOutput.ConsumedCapacity)
+
+	success := true
+	return &success, nil
+}
+
+// RenameUser updates the user as UpdateUser does, moving its username claim from the previous username
+// to the new one in the same transaction. The rename fails when another user holds the new username,
+// or when the user was renamed since previousUsername was read.
+func (DynamoDbStore *DynamoDbStore) RenameUser(ctx context.Context, userId string, previousUsername string, group *cfm.User) (*bool, error) {
+	if DryRunFromContext(ctx) {
+		return DynamoDbStore.UpdateUser(ctx, userId, group)
+	}
+
+	ctx, span := DynamoDbStore.startSpan(ctx, "RenameUser", "TransactWriteItems", nil)
+	defer span.End()
+
+	update, err := DynamoDbStore.userUpdate(userId, group)
+	if err != nil {
+		return nil, err
+	}
+
+	previousUsernameAttribute, _ := attributevalue.Marshal(previousUsername)
+	userIdAttribute, _ := attributevalue.Marshal(userId)
+
+	updateCondition := *update.ConditionExpression + " and #Username = :previous_username"
+	update.ConditionExpression = &updateCondition
+	update.ExpressionAttributeValues[":previous_username"] = previousUsernameAttribute
+	update.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+
+	claimItem, err := attributevalue.MarshalMap(uniqueClaim(usernameClaimPrefix, group.Username, userId))
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert UniqueClaim to Attribute Value map: %v", err.Error())
+	}
+
+	previousClaim := uniqueClaim(usernameClaimPrefix, previousUsername, userId)
+	previousClaimPk, _ := attributevalue.Marshal(previousClaim.PK)
+	previousClaimSk, _ := attributevalue.Marshal(previousClaim.SK)
+
+	claimCondition := "attribute_not_exists(PK)"
+	// Users created before username claims existed have no claim to release
+	releaseCondition := "attribute_not_exists(PK) or UserId = :user_id"
+
+	transactInput := &dynamodb.TransactWriteItemsInput{
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: update,
+			},
+			{
+				Put: &types.Put{
+					TableName:           &DynamoDbStore.tableName,
+					Item:                claimItem,
+					ConditionExpression: &claimCondition,
+				},
+			},
+			{
+				Delete: &types.Delete{
+					TableName: &DynamoDbStore.tableName,
+					Key: map[string]types.AttributeValue{
+						"PK": previousClaimPk,
+						"SK": previousClaimSk,
+					},
+					ConditionExpression: &releaseCondition,
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":user_id": userIdAttribute,
+					},
+				},
+			},
+		},
+	}
+
+	callStart := time.Now()
+	transactOutput, err := DynamoDbStore.dynamoDb.TransactWriteItems(ctx, transactInput)
+	if reason := failedCondition(err, 0); reason != nil {
+		DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+		if len(reason.Item) == 0 {
+			return nil, cfe.ErrorNotFound()
+		}
+
+		return nil, cfe.ErrorValidation(fmt.Sprintf("User was renamed since it was read: %v", previousUsername))
+	}
+	if failedCondition(err, 1) != nil {
+		DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+		return nil, cfe.ErrorValidation(fmt.Sprintf("User already exists with given username: %v", group.Username))
+	}
+
+	DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to rename user: %v", err.Error())
+	}
+
+	for _, consumedCapacity := range transactOutput.ConsumedCapacity {
+		recordConsumedCapacity(ctx, "RenameUser", &consumedCapacity)
+	}
+
+	success := true
+	return &success, nil
+}
+
+// LinkIdentitySubject links the user to an identity provider subject, moving its subject claim from the
+// previous subject, if any, in the same transaction. Linking fails when another user holds the subject.
+func (DynamoDbStore *DynamoDbStore) LinkIdentitySubject(ctx context.Context, userId string, previousSubject *string, subject string) (*bool, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "LinkIdentitySubject", "TransactWriteItems", nil)
+	defer span.End()
+
+	pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
+	skAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
+	subjectAttribute, _ := attributevalue.Marshal(subject)
+	gsi3pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("SUBJECT#%v", subject))
+	gsi3skAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
+	updatedDateAttribute, _ := attributevalue.Marshal(time.Now().UTC())
+
+	conditionExpression := "attribute_exists(PK) and attribute_exists(SK)"
+	attributeValues := map[string]types.AttributeValue{
+		":subject":      subjectAttribute,
+		":gsi3pk":       gsi3pkAttribute,
+		":gsi3sk":       gsi3skAttribute,
+		":updated_date": updatedDateAttribute,
+	}
+
+	// Guards against a concurrent link replacing the subject read by the caller
+	if previousSubject != nil {
+		previousSubjectAttribute, _ := attributevalue.Marshal(*previousSubject)
+
+		conditionExpression += " and IdentitySubject = :previous_subject"
+		attributeValues[":previous_subject"] = previousSubjectAttribute
+	} else {
+		conditionExpression += " and attribute_not_exists(IdentitySubject)"
+	}
+
+	if DryRunFromContext(ctx) {
+		passed, err := DynamoDbStore.checkCondition(ctx, "LinkIdentitySubject", pkAttribute, skAttribute, conditionExpression, attributeValues)
+		if err != nil {
+			return nil, err
+		}
+		if !passed {
+			return nil, cfe.ErrorNotFound()
+		}
+
+		success := true
+		return &success, nil
+	}
+
+	updateExpression := "SET IdentitySubject = :subject, GSI3PK = :gsi3pk, GSI3SK = :gsi3sk, UpdatedDate = :updated_date"
+
+	claimItem, err := attributevalue.MarshalMap(uniqueClaim(subjectClaimPrefix, subject, userId))
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert UniqueClaim to Attribute Value map: %v", err.Error())
+	}
+
+	claimCondition := "attribute_not_exists(PK)"
+
+	transactInput := &dynamodb.TransactWriteItemsInput{
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName: &DynamoDbStore.tableName,
+					Key: map[string]types.AttributeValue{
+						"PK": pkAttribute,
+						"SK": skAttribute,
+					},
+					ConditionExpression:                 &conditionExpression,
+					UpdateExpression:                    &updateExpression,
+					ExpressionAttributeValues:           attributeValues,
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           &DynamoDbStore.tableName,
+					Item:                claimItem,
+					ConditionExpression: &claimCondition,
+				},
+			},
+		},
+	}
+
+	if previousSubject != nil {
+		previousClaim := uniqueClaim(subjectClaimPrefix, *previousSubject, userId)
+		previousClaimPk, _ := attributevalue.Marshal(previousClaim.PK)
+		previousClaimSk, _ := attributevalue.Marshal(previousClaim.SK)
+		userIdAttribute, _ := attributevalue.Marshal(userId)
+
+		// Users linked before subject claims existed have no claim to release
+		releaseCondition := "attribute_not_exists(PK) or UserId = :user_id"
+
+		transactInput.TransactItems = append(transactInput.TransactItems, types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName: &DynamoDbStore.tableName,
+				Key: map[string]types.AttributeValue{
+					"PK": previousClaimPk,
+					"SK": previousClaimSk,
+				},
+				ConditionExpression: &releaseCondition,
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":user_id": userIdAttribute,
+				},
+			},
+		})
+	}
+
+	callStart := time.Now()
+	transactOutput, err := DynamoDbStore.dynamoDb.TransactWriteItems(ctx, transactInput)
+	if reason := failedCondition(err, 0); reason != nil {
+		DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+		if len(reason.Item) == 0 {
+			return nil, cfe.ErrorNotFound()
+		}
+
+		return nil, cfe.ErrorValidation("User was linked to another identity subject since it was read.")
+	}
+	if failedCondition(err, 1) != nil {
+		DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+		return nil, cfe.ErrorValidation("User already exists for the given identity subject.")
+	}
+
+	DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to link identity subject: %v", err.Error())
+	}
+
+	for _, consumedCapacity := range transactOutput.ConsumedCapacity {
+		recordConsumedCapacity(ctx, "LinkIdentitySubject", &consumedCapacity)
+	}
+
+	success := true
+	return &success, nil
+}
+
+func (DynamoDbStore *DynamoDbStore) DeleteUser(ctx context.Context, userId string) (*bool, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "DeleteUser", "DeleteItem", nil)
+	defer span.End()
+
+	pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
+	skAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
+
+	if DryRunFromContext(ctx) {
+		// Unlike the delete itself, a dry run reports users that do not exist
+		passed, err := DynamoDbStore.checkCondition(ctx, "DeleteUser", pkAttribute, skAttribute, "attribute_exists(PK) and attribute_exists(SK)", nil)
+		if err != nil {
+			return nil, err
+		}
+		if !passed {
+			return nil, cfe.ErrorNotFound()
+		}
+
+		success := true
+		return &success, nil
+	}
+
+	deleteInput := &dynamodb.DeleteItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		Key: map[string]types.AttributeValue{
+			"PK": pkAttribute,
+			"SK": skAttribute,
+		},
+		ReturnValues: types.ReturnValueAllOld,
+	}
+
+	callStart := time.Now()
+	deleteOutput, err := DynamoDbStore.dynamoDb.DeleteItem(ctx, deleteInput)
+	DynamoDbStore.observeCall(ctx, "DeleteItem", callStart, deleteInput, deleteOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to delete user: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "DeleteUser", deleteOutput.ConsumedCapacity)
+
+	var deleted cfm.User
+	if err := attributevalue.UnmarshalMap(deleteOutput.Attributes, &deleted); err != nil {
+		return nil, fmt.Errorf("unable to parse user from deleted item: %v", err)
+	}
+
+	if len(deleted.UserId) > 0 {
+		if err := DynamoDbStore.releaseClaim(ctx, uniqueClaim(usernameClaimPrefix, deleted.Username, deleted.UserId)); err != nil {
+			return nil, err
+		}
+	}
+	if deleted.IdentitySubject != nil {
+		if err := DynamoDbStore.releaseClaim(ctx, uniqueClaim(subjectClaimPrefix, *deleted.IdentitySubject, deleted.UserId)); err != nil {
+			return nil, err
+		}
+	}
+
+	success := true
+	return &success, nil
+}
+
+// StartIdempotentRequest claims the idempotency key of the record. When the key is already claimed,
+// by an unexpired record or an in progress request that still holds its lock, the existing record is returned.
+func (DynamoDbStore *DynamoDbStore) StartIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord) (*cfm.IdempotencyRecord, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "StartIdempotentRequest", "PutItem", nil)
+	defer span.End()
+
 	now := time.Now().UTC()
 
+	record.Status = cfe.IdempotencyInProgress.String()
+	record.CreatedDate = now
+	record.UpdatedDate = now
+
+	nowAttribute, _ := attributevalue.Marshal(now.Unix())
+	inProgressAttribute, _ := attributevalue.Marshal(cfe.IdempotencyInProgress.String())
+
+	conditionExpression := "attribute_not_exists(PK) or ExpiresAt < :now or (#Status = :in_progress and LockExpiresAt < :now)"
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert IdempotencyRecord to Attribute Value map: %v", err.Error())
+	}
+
+	putInput := &dynamodb.PutItemInput{
+		TableName:                           &DynamoDbStore.tableName,
+		ReturnConsumedCapacity:              types.ReturnConsumedCapacityIndexes,
+		Item:                                item,
+		ConditionExpression:                 &conditionExpression,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		ExpressionAttributeNames: map[string]string{
+			"#Status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":         nowAttribute,
+			":in_progress": inProgressAttribute,
+		},
+	}
+
+	callStart := time.Now()
+	putOutput, err := DynamoDbStore.dynamoDb.PutItem(ctx, putInput)
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		DynamoDbStore.observeCall(ctx, "PutItem", callStart, putInput, putOutput, nil)
+
+		var existing cfm.IdempotencyRecord
+		err = attributevalue.UnmarshalMap(conditionFailed.Item, &existing)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse idempotency record from returned item: %v", err)
+		}
+
+		return &existing, nil
+	}
+
+	DynamoDbStore.observeCall(ctx, "PutItem", callStart, putInput, putOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start idempotent request: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "StartIdempotentRequest", putOutput.ConsumedCapacity)
+
+	return nil, nil
+}
+
+func (DynamoDbStore *DynamoDbStore) CompleteIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord) error {
+	ctx, span := DynamoDbStore.startSpan(ctx, "CompleteIdempotentRequest", "PutItem", nil)
+	defer span.End()
+
+	record.Status = cfe.IdempotencyCompleted.String()
+	record.UpdatedDate = time.Now().UTC()
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return fmt.Errorf("unable to convert IdempotencyRecord to Attribute Value map: %v", err.Error())
+	}
+
+	putInput := &dynamodb.PutItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		Item:                   item,
+	}
+
+	callStart := time.Now()
+	putOutput, err := DynamoDbStore.dynamoDb.PutItem(ctx, putInput)
+	DynamoDbStore.observeCall(ctx, "PutItem", callStart, putInput, putOutput, err)
+	if err != nil {
+		return fmt.Errorf("unable to complete idempotent request: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "CompleteIdempotentRequest", putOutput.ConsumedCapacity)
+
+	return nil
+}
+
+func (DynamoDbStore *DynamoDbStore) DeleteIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord) error {
+	ctx, span := DynamoDbStore.startSpan(ctx, "DeleteIdempotentRequest", "DeleteItem", nil)
+	defer span.End()
+
+	pkAttribute, _ := attributevalue.Marshal(record.PK)
+	skAttribute, _ := attributevalue.Marshal(record.SK)
+
+	deleteInput := &dynamodb.DeleteItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		Key: map[string]types.AttributeValue{
+			"PK": pkAttribute,
+			"SK": skAttribute,
+		},
+	}
+
+	callStart := time.Now()
+	deleteOutput, err := DynamoDbStore.dynamoDb.DeleteItem(ctx, deleteInput)
+	DynamoDbStore.observeCall(ctx, "DeleteItem", callStart, deleteInput, deleteOutput, err)
+	if err != nil {
+		return fmt.Errorf("unable to delete idempotent request: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "DeleteIdempotentRequest", deleteOutput.ConsumedCapacity)
+
+	return nil
+}
+
+func (DynamoDbStore *DynamoDbStore) GetRateLimitBucket(ctx context.Context, pk string, sk string) (*cfm.RateLimitBucket, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "GetRateLimitBucket", "GetItem", nil)
+	defer span.End()
+
+	pkAttribute, _ := attributevalue.Marshal(pk)
+	skAttribute, _ := attributevalue.Marshal(sk)
+	consistentRead := true
+
+	queryInput := &dynamodb.GetItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		ConsistentRead:         &consistentRead,
+		Key: map[string]types.AttributeValue{
+			"PK": pkAttribute,
+			"SK": skAttribute,
+		},
+	}
+
+	callStart := time.Now()
+	response, err := DynamoDbStore.dynamoDb.GetItem(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "GetItem", callStart, queryInput, response, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch rate limit bucket: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "GetRateLimitBucket", response.ConsumedCapacity)
+
+	if response.Item == nil {
+		return nil, nil
+	}
+
+	var bucket cfm.RateLimitBucket
+	err = attributevalue.UnmarshalMap(response.Item, &bucket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rate limit bucket from returned item: %v", err)
+	}
+
+	return &bucket, nil
+}
+
+// SaveRateLimitBucket only writes the bucket when it was not modified since it was read,
+// returning false when a concurrent request updated it first.
+func (DynamoDbStore *DynamoDbStore) SaveRateLimitBucket(ctx context.Context, bucket *cfm.RateLimitBucket, previousUpdatedAt *int64) (*bool, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "SaveRateLimitBucket", "PutItem", nil)
+	defer span.End()
+
+	item, err := attributevalue.MarshalMap(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert RateLimitBucket to Attribute Value map: %v", err.Error())
+	}
+
+	putInput := &dynamodb.PutItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		Item:                   item,
+	}
+
+	if previousUpdatedAt == nil {
+		conditionExpression := "attribute_not_exists(PK)"
+		putInput.ConditionExpression = &conditionExpression
+	} else {
+		conditionExpression := "UpdatedAt = :updated_at"
+		updatedAtAttribute, _ := attributevalue.Marshal(*previousUpdatedAt)
+
+		putInput.ConditionExpression = &conditionExpression
+		putInput.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":updated_at": updatedAtAttribute,
+		}
+	}
+
+	callStart := time.Now()
+	putOutput, err := DynamoDbStore.dynamoDb.PutItem(ctx, putInput)
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		DynamoDbStore.observeCall(ctx, "PutItem", callStart, putInput, putOutput, nil)
+
+		saved := false
+		return &saved, nil
+	}
+
+	DynamoDbStore.observeCall(ctx, "PutItem", callStart, putInput, putOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to save rate limit bucket: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "SaveRateLimitBucket", putOutput.ConsumedCapacity)
+
+	saved := true
+	return &saved, nil
+}
+
+func (DynamoDbStore *DynamoDbStore) TableName() string {
+	return DynamoDbStore.tableName
+}
+
+// IndexNames returns the global secondary indexes the store queries.
+func (DynamoDbStore *DynamoDbStore) IndexNames() []string {
+	return []string{gsi1IndexName, gsi2IndexName, gsi3IndexName}
+}
+
+// DescribeUserTable returns the status of the table, keyed by its name, and of each of its global
+// secondary indexes, keyed by index name.
+func (DynamoDbStore *DynamoDbStore) DescribeUserTable(ctx context.Context) (map[string]string, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, "DescribeUserTable", "DescribeTable", nil)
+	defer span.End()
+
+	describeInput := &dynamodb.DescribeTableInput{
+		TableName: &DynamoDbStore.tableName,
+	}
+
+	callStart := time.Now()
+	describeOutput, err := DynamoDbStore.dynamoDb.DescribeTable(ctx, describeInput)
+	DynamoDbStore.observeCall(ctx, "DescribeTable", callStart, describeInput, describeOutput, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe user table: %v", err.Error())
+	}
+
+	statuses := map[string]string{
+		DynamoDbStore.tableName: string(describeOutput.Table.TableStatus),
+	}
+	for _, index := range describeOutput.Table.GlobalSecondaryIndexes {
+		if index.IndexName != nil {
+			statuses[*index.IndexName] = string(index.IndexStatus)
+		}
+	}
+
+	return statuses, nil
+}
+
+// ProbeUserTable reads an item that never exists, proving the table can be read without touching user data.
+func (DynamoDbStore *DynamoDbStore) ProbeUserTable(ctx context.Context) error {
+	ctx, span := DynamoDbStore.startSpan(ctx, "ProbeUserTable", "GetItem", nil)
+	defer span.End()
+
+	probeAttribute, _ := attributevalue.Marshal("HEALTH#probe")
+	projection := "PK"
+
+	queryInput := &dynamodb.GetItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		ProjectionExpression:   &projection,
+		Key: map[string]types.AttributeValue{
+			"PK": probeAttribute,
+			"SK": probeAttribute,
+		},
+	}
+
+	callStart := time.Now()
+	response, err := DynamoDbStore.dynamoDb.GetItem(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "GetItem", callStart, queryInput, response, err)
+	if err != nil {
+		return fmt.Errorf("unable to probe user table: %v", err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "ProbeUserTable", response.ConsumedCapacity)
+
+	return nil
+}
+
+// ProbeUserIndex queries a global secondary index for a partition that never exists.
+func (DynamoDbStore *DynamoDbStore) ProbeUserIndex(ctx context.Context, indexName string) error {
+	ctx, span := DynamoDbStore.startSpan(ctx, "ProbeUserIndex", "Query", &indexName)
+	defer span.End()
+
+	probeAttribute, _ := attributevalue.Marshal("HEALTH#probe")
+
+	keyCondition := fmt.Sprintf("%vPK = :pk", indexName)
+	limit := int32(1)
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		IndexName:              &indexName,
+		KeyConditionExpression: &keyCondition,
+		Limit:                  &limit,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": probeAttribute,
+		},
+	}
+
+	callStart := time.Now()
+	page, err := DynamoDbStore.dynamoDb.Query(ctx, queryInput)
+	DynamoDbStore.observeCall(ctx, "Query", callStart, queryInput, page, err)
+	if err != nil {
+		return fmt.Errorf("unable to probe user index %v: %v", indexName, err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "ProbeUserIndex", page.ConsumedCapacity)
+
+	return nil
+}
+
+// DynamoDb Helper Functions
+func (DynamoDbStore *DynamoDbStore) observeCall(ctx context.Context, operation string, start time.Time, input interface{}, output interface{}, err error) {
+	MetricsFromContext(ctx).AddDuration("DynamoDbLatency", start, MetricDimension{Name: "Operation", Value: operation})
+
+	logDynamoDbCall(ctx, operation, input, output, err)
+
+	recordSpanError(trace.SpanFromContext(ctx), err)
+}
+
+// checkCondition evaluates the condition of a write as a ConditionCheck-only transaction, so dry runs
+// find out whether the write would succeed without persisting anything.
+func (DynamoDbStore *DynamoDbStore) checkCondition(ctx context.Context, method string, pkAttribute types.AttributeValue, skAttribute types.AttributeValue, conditionExpression string, attributeValues map[string]types.AttributeValue) (bool, error) {
+	ctx, span := DynamoDbStore.startSpan(ctx, method+"DryRun", "TransactWriteItems", nil)
+	defer span.End()
+
+	transactInput := &dynamodb.TransactWriteItemsInput{
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		TransactItems: []types.TransactWriteItem{
+			{
+				ConditionCheck: &types.ConditionCheck{
+					TableName: &DynamoDbStore.tableName,
+					Key: map[string]types.AttributeValue{
+						"PK": pkAttribute,
+						"SK": skAttribute,
+					},
+					ConditionExpression:       &conditionExpression,
+					ExpressionAttributeValues: attributeValues,
+				},
+			},
+		},
+	}
+
+	callStart := time.Now()
+	transactOutput, err := DynamoDbStore.dynamoDb.TransactWriteItems(ctx, transactInput)
+
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 {
+		reason := canceled.CancellationReasons[0]
+		if reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+			DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, nil)
+			return false, nil
+		}
+	}
+
+	DynamoDbStore.observeCall(ctx, "TransactWriteItems", callStart, transactInput, transactOutput, err)
+	if err != nil {
+		return false, fmt.Errorf("unable to check condition for %v: %v", method, err.Error())
+	}
+
+	for _, consumedCapacity := range transactOutput.ConsumedCapacity {
+		recordConsumedCapacity(ctx, method+"DryRun", &consumedCapacity)
+	}
+
+	return true, nil
+}
+
+// releaseClaim deletes the user's claim, leaving claims held by other users.
+func (DynamoDbStore *DynamoDbStore) releaseClaim(ctx context.Context, claim *cfm.UniqueClaim) error {
+
+	pkAttribute, _ := attributevalue.Marshal(claim.PK)
+	skAttribute, _ := attributevalue.Marshal(claim.SK)
+	userIdAttribute, _ := attributevalue.Marshal(claim.UserId)
+
+	conditionExpression := "attribute_not_exists(PK) or UserId = :user_id"
+
+	deleteInput := &dynamodb.DeleteItemInput{
+		TableName:              &DynamoDbStore.tableName,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		Key: map[string]types.AttributeValue{
+			"PK": pkAttribute,
+			"SK": skAttribute,
+		},
+		ConditionExpression: &conditionExpression,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user_id": userIdAttribute,
+		},
+	}
+
+	callStart := time.Now()
+	deleteOutput, err := DynamoDbStore.dynamoDb.DeleteItem(ctx, deleteInput)
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		DynamoDbStore.observeCall(ctx, "DeleteItem", callStart, deleteInput, deleteOutput, nil)
+		return nil
+	}
+
+	DynamoDbStore.observeCall(ctx, "DeleteItem", callStart, deleteInput, deleteOutput, err)
+	if err != nil {
+		return fmt.Errorf("unable to release claim on %v: %v", claim.PK, err.Error())
+	}
+
+	recordConsumedCapacity(ctx, "ReleaseClaim", deleteOutput.ConsumedCapacity)
+
+	return nil
+}
+
+// userUpdate builds the update of the user's mutable attributes, which requires the user to exist.
+func (DynamoDbStore *DynamoDbStore) userUpdate(userId string, group *cfm.User) (*types.Update, error) {
 	pk := fmt.Sprintf("USER#%v", userId)
 	sk := fmt.Sprintf("USER#%v", userId)
 
 	pkAttribute, _ := attributevalue.Marshal(pk)
 	skAttribute, _ := attributevalue.Marshal(sk)
 
+	gsi1pk := fmt.Sprintf("USERNAME#%v", group.Username)
+
 	group.PK = &pk
 	group.SK = &sk
-	group.UpdatedDate = now
+	group.GSI1PK = &gsi1pk
+	group.UpdatedDate = time.Now().UTC()
 
 	conditionExpression := "attribute_exists(PK) and attribute_exists(SK)"
 
 	updateFields := []string{
+		"Username",
+		"GSI1PK",
+		"AccountType",
 		"FirstName",
 		"LastName",
 		"PhoneNumber",
@@ -256,7 +1176,7 @@ func (DynamoDbStore *DynamoDbStore) UpdateUser(userId string, group *cfm.User) (
 		return nil, fmt.Errorf("unable to convert User to Attribute Value map: %v", err.Error())
 	}
 
-	updateInput := &dynamodb.UpdateItemInput{
+	return &types.Update{
 		TableName: &DynamoDbStore.tableName,
 		Key: map[string]types.AttributeValue{
 			"PK": pkAttribute,
@@ -266,39 +1186,57 @@ func (DynamoDbStore *DynamoDbStore) UpdateUser(userId string, group *cfm.User) (
 		UpdateExpression:          updateExpression,
 		ExpressionAttributeNames:  *attributeNames,
 		ExpressionAttributeValues: *attributeValues,
+	}, nil
+}
+
+func uniqueClaim(prefix string, value string, userId string) *cfm.UniqueClaim {
+	return &cfm.UniqueClaim{
+		PK:     fmt.Sprintf("%v#%v", prefix, value),
+		SK:     fmt.Sprintf("%v#%v", prefix, value),
+		UserId: userId,
 	}
+}
 
-	_, err = DynamoDbStore.dynamoDb.UpdateItem(context.TODO(), updateInput)
-	if err != nil {
-		return nil, fmt.Errorf("unable to update user: %v", err.Error())
+// failedCondition returns the cancellation reason of the transaction item at index when the
+// transaction was canceled because that item's condition failed.
+func failedCondition(err error, index int) *types.CancellationReason {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) <= index {
+		return nil
 	}
 
-	success := true
-	return &success, nil
-}
+	reason := canceled.CancellationReasons[index]
+	if reason.Code == nil || *reason.Code != "ConditionalCheckFailed" {
+		return nil
+	}
 
-func (DynamoDbStore *DynamoDbStore) DeleteUser(userId string) (*bool, error) {
-	pkAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
-	skAttribute, _ := attributevalue.Marshal(fmt.Sprintf("USER#%v", userId))
+	return &reason
+}
 
-	deleteInput := &dynamodb.DeleteItemInput{
-		TableName: &DynamoDbStore.tableName,
-		Key: map[string]types.AttributeValue{
-			"PK": pkAttribute,
-			"SK": skAttribute,
-		},
+// projectionExpression reads only the given attributes, or the whole item when none are given.
+func projectionExpression(attributes []string) (*string, map[string]string) {
+	if len(attributes) == 0 {
+		return nil, nil
 	}
 
-	_, err := DynamoDbStore.dynamoDb.DeleteItem(context.TODO(), deleteInput)
-	if err != nil {
-		return nil, fmt.Errorf("unable to delete user: %v", err.Error())
+	placeholders := make([]string, 0, len(attributes))
+	attributeNames := make(map[string]string)
+
+	for _, attribute := range attributes {
+		placeholder := fmt.Sprintf("#%v", attribute)
+		if _, ok := attributeNames[placeholder]; ok {
+			continue
+		}
+
+		placeholders = append(placeholders, placeholder)
+		attributeNames[placeholder] = attribute
 	}
 
-	success := true
-	return &success, nil
+	expression := strings.Join(placeholders, ", ")
+
+	return &expression, attributeNames
 }
 
-// DynamoDb Helper Functions
 func extractAttributeUpdateValues[T interface{}](entity T, fieldNames ...string) (expression *string, attributeNames *map[string]string, attributeValues *map[string]types.AttributeValue, err error) {
 	updateExpression := make([]string, 0)
 	expressionAttributeNames := make(map[string]string)
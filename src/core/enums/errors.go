@@ -15,6 +15,12 @@ const (
 	ErrorCodeValidationFailed
 	ErrorCodeAccessDenied
 	ErrorCodeUnhandledException
+	ErrorCodeIdempotencyKeyReused
+	ErrorCodeRequestInProgress
+	ErrorCodeTooManyRequests
+	ErrorCodePayloadTooLarge
+	ErrorCodeAlreadyExists
+	ErrorCodeMaintenance
 )
 
 func (priority ErrorCode) String() string {
@@ -23,20 +29,44 @@ func (priority ErrorCode) String() string {
 		"VALIDATION_FAILED",
 		"ACCESS_DENIED",
 		"UNHANDLED_EXCEPTION",
+		"IDEMPOTENCY_KEY_REUSED",
+		"REQUEST_IN_PROGRESS",
+		"TOO_MANY_REQUESTS",
+		"PAYLOAD_TOO_LARGE",
+		"ALREADY_EXISTS",
+		"MAINTENANCE",
 	}[priority]
 }
 
 type ResponseError struct {
-	ErrorMessage string   `json:"errorMessage"`
-	ErrorStatus  int      `json:"-"`
-	ErrorCode    string   `json:"errorCode"`
-	Errors       []string `json:"errors"`
+	ErrorMessage  string       `json:"errorMessage"`
+	ErrorStatus   int          `json:"-"`
+	ErrorCode     string       `json:"errorCode"`
+	Errors        []string     `json:"errors"`
+	FieldErrors   []FieldError `json:"fieldErrors,omitempty"`
+	CorrelationId string       `json:"correlationId,omitempty"`
+	ErrorId       string       `json:"errorId,omitempty"` // references the logged and reported details of unhandled errors
+}
+
+// FieldError names the request field that failed, using its JSON path or parameter name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func (resError *ResponseError) AddData(value string) {
 	resError.Errors = append(resError.Errors, value)
 }
 
+func (resError *ResponseError) AddFieldError(field string, code string, message string) {
+	resError.FieldErrors = append(resError.FieldErrors, FieldError{
+		Field:   field,
+		Code:    code,
+		Message: message,
+	})
+}
+
 func (resError ResponseError) Error() string {
 	val, _ := json.Marshal(resError)
 
@@ -46,7 +76,10 @@ func (resError ResponseError) Error() string {
 func (resError ResponseError) ApiResponse() events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
 		StatusCode: resError.ErrorStatus,
-		Body:       resError.Error(),
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: resError.Error(),
 	}
 }
 
@@ -77,6 +110,42 @@ func ErrorAuthorization(msg string) ResponseError {
 	}
 }
 
+func ErrorConflict(errorCode ErrorCode, msg string) ResponseError {
+	return ResponseError{
+		ErrorMessage: fmt.Sprintf("Conflict: %v", msg),
+		ErrorStatus:  http.StatusConflict,
+		ErrorCode:    errorCode.String(),
+		Errors:       make([]string, 0),
+	}
+}
+
+func ErrorTooManyRequests(msg string) ResponseError {
+	return ResponseError{
+		ErrorMessage: fmt.Sprintf("Too Many Requests: %v", msg),
+		ErrorStatus:  http.StatusTooManyRequests,
+		ErrorCode:    ErrorCodeTooManyRequests.String(),
+		Errors:       make([]string, 0),
+	}
+}
+
+func ErrorPayloadTooLarge(msg string) ResponseError {
+	return ResponseError{
+		ErrorMessage: fmt.Sprintf("Payload Too Large: %v", msg),
+		ErrorStatus:  http.StatusRequestEntityTooLarge,
+		ErrorCode:    ErrorCodePayloadTooLarge.String(),
+		Errors:       make([]string, 0),
+	}
+}
+
+func ErrorMaintenance(msg string) ResponseError {
+	return ResponseError{
+		ErrorMessage: fmt.Sprintf("Service Unavailable: %v", msg),
+		ErrorStatus:  http.StatusServiceUnavailable,
+		ErrorCode:    ErrorCodeMaintenance.String(),
+		Errors:       make([]string, 0),
+	}
+}
+
 func ErrorUnhandled(msg string) ResponseError {
 	return ResponseError{
 		ErrorMessage: fmt.Sprintf("Unhandled Exception: %v", msg),
@@ -0,0 +1,18 @@
+package enums
+
+// HealthStatus is ordered by severity, so the overall status of a health check is the highest of its checks.
+type HealthStatus int
+
+const (
+	HealthPass HealthStatus = iota
+	HealthWarn
+	HealthFail
+)
+
+func (status HealthStatus) String() string {
+	return [...]string{
+		"pass",
+		"warn",
+		"fail",
+	}[status]
+}
@@ -0,0 +1,15 @@
+package enums
+
+type IdempotencyStatus int
+
+const (
+	IdempotencyInProgress IdempotencyStatus = iota
+	IdempotencyCompleted
+)
+
+func (status IdempotencyStatus) String() string {
+	return [...]string{
+		"IN_PROGRESS",
+		"COMPLETED",
+	}[status]
+}
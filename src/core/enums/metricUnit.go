@@ -0,0 +1,17 @@
+package enums
+
+type MetricUnit int
+
+const (
+	MetricUnitCount MetricUnit = iota
+	MetricUnitMilliseconds
+	MetricUnitNone
+)
+
+func (unit MetricUnit) String() string {
+	return [...]string{
+		"Count",
+		"Milliseconds",
+		"None",
+	}[unit]
+}
@@ -0,0 +1,150 @@
+package enums
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SuperAdminPermission satisfies every requirement, for operators that need unrestricted access.
+const SuperAdminPermission = "cf:superadmin"
+
+const permissionWildcard = "*"
+
+// Permissions are the "cf:<action>:<resource>" grants passed by the authorizer. A grant may
+// use "*" for any segment, so "cf:*:user" allows every action on users.
+type Permissions []string
+
+// AuthRequirement is satisfied by a caller's permissions, and can be combined using AnyOf and AllOf.
+type AuthRequirement interface {
+	IsSatisfiedBy(permissions Permissions) bool
+	String() string
+}
+
+type anyOfRequirement []AuthRequirement
+type allOfRequirement []AuthRequirement
+
+func AnyOf(requirements ...AuthRequirement) AuthRequirement {
+	return anyOfRequirement(requirements)
+}
+
+func AllOf(requirements ...AuthRequirement) AuthRequirement {
+	return allOfRequirement(requirements)
+}
+
+func (requirements anyOfRequirement) IsSatisfiedBy(permissions Permissions) bool {
+	for _, requirement := range requirements {
+		if requirement.IsSatisfiedBy(permissions) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (requirements anyOfRequirement) String() string {
+	return joinRequirements("anyOf", requirements)
+}
+
+func (requirements allOfRequirement) IsSatisfiedBy(permissions Permissions) bool {
+	if len(requirements) == 0 {
+		return false
+	}
+
+	for _, requirement := range requirements {
+		if !requirement.IsSatisfiedBy(permissions) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (requirements allOfRequirement) String() string {
+	return joinRequirements("allOf", requirements)
+}
+
+func joinRequirements(name string, requirements []AuthRequirement) string {
+	values := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		values = append(values, requirement.String())
+	}
+
+	return name + "(" + strings.Join(values, ",") + ")"
+}
+
+// GetPermissions reads the "permissions" claim from the authorizer context. The claim may be a
+// comma or space separated string, a JSON encoded array, or an array of strings.
+func GetPermissions(authContext map[string]interface{}) Permissions {
+	claim, ok := authContext["permissions"]
+	if !ok || claim == nil {
+		return Permissions{}
+	}
+
+	var values []string
+
+	switch typedClaim := claim.(type) {
+	case string:
+		trimmed := strings.TrimSpace(typedClaim)
+		if strings.HasPrefix(trimmed, "[") && json.Unmarshal([]byte(trimmed), &values) == nil {
+			break
+		}
+
+		values = strings.FieldsFunc(trimmed, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	case []string:
+		values = typedClaim
+	case []interface{}:
+		for _, item := range typedClaim {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	permissions := make(Permissions, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) > 0 {
+			permissions = append(permissions, value)
+		}
+	}
+
+	return permissions
+}
+
+// Contains reports whether the permission is held as is, ignoring wildcards and cf:superadmin, for
+// break-glass permissions that must be granted explicitly.
+func (permissions Permissions) Contains(permission string) bool {
+	return slices.Contains(permissions, permission)
+}
+
+// Grants reports whether any held permission matches the required one, honouring wildcards.
+func (permissions Permissions) Grants(required string) bool {
+	for _, permission := range permissions {
+		if permission == SuperAdminPermission || matchPermission(permission, required) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchPermission(granted string, required string) bool {
+	grantedSegments := strings.Split(granted, ":")
+	requiredSegments := strings.Split(required, ":")
+
+	if len(grantedSegments) != len(requiredSegments) {
+		return false
+	}
+
+	for i, segment := range grantedSegments {
+		if segment != permissionWildcard && segment != requiredSegments[i] {
+			return false
+		}
+	}
+
+	return true
+}
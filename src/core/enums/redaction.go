@@ -0,0 +1,44 @@
+package enums
+
+import (
+	"fmt"
+)
+
+type RedactionPolicy int
+
+const (
+	RedactionPolicyNone RedactionPolicy = iota
+	RedactionPolicyStandard
+	RedactionPolicyStrict
+)
+
+func (policy RedactionPolicy) String() string {
+	return [...]string{
+		"none",
+		"standard",
+		"strict",
+	}[policy]
+}
+
+// GetRedactionPolicy falls back to the strict policy when no policy is configured,
+// so a missing setting never results in PII being written to the logs.
+func GetRedactionPolicy(policy *string) (*RedactionPolicy, error) {
+	var matchedPolicy RedactionPolicy
+
+	if policy == nil || len(*policy) == 0 {
+		matchedPolicy = RedactionPolicyStrict
+	} else {
+		switch *policy {
+		case RedactionPolicyNone.String():
+			matchedPolicy = RedactionPolicyNone
+		case RedactionPolicyStandard.String():
+			matchedPolicy = RedactionPolicyStandard
+		case RedactionPolicyStrict.String():
+			matchedPolicy = RedactionPolicyStrict
+		default:
+			return nil, fmt.Errorf("no matching redaction policy found for: %v", *policy)
+		}
+	}
+
+	return &matchedPolicy, nil
+}
@@ -1,9 +1,5 @@
 package enums
 
-import (
-	"strings"
-)
-
 type LambdaRole int
 
 const (
@@ -11,6 +7,9 @@ const (
 	UpdateUser
 	DeleteUser
 	ReadUser
+	AdminUser
+	BypassMaintenance
+	ReadHealth
 )
 
 func (role LambdaRole) String() string {
@@ -19,21 +18,21 @@ func (role LambdaRole) String() string {
 		"cf:update:user",
 		"cf:delete:user",
 		"cf:read:user",
+		"cf:admin:user",
+		"cf:bypass:maintenance",
+		"cf:read:health",
 	}[role]
 }
 
-func (role LambdaRole) ExistsInAuthContext(authContext map[string]interface{}) bool {
-	permissions, ok := authContext["permissions"]
-	if !ok {
-		return false
-	}
+// IsWrite reports whether the role modifies users, and is therefore blocked in read-only mode.
+func (role LambdaRole) IsWrite() bool {
+	return role == CreateUser || role == UpdateUser || role == DeleteUser
+}
 
-	permissionsArray := strings.Split(permissions.(string), ",")
-	for _, r := range permissionsArray {
-		if r == role.String() {
-			return true
-		}
-	}
+func (role LambdaRole) IsSatisfiedBy(permissions Permissions) bool {
+	return permissions.Grants(role.String())
+}
 
-	return false
+func (role LambdaRole) ExistsInAuthContext(authContext map[string]interface{}) bool {
+	return role.IsSatisfiedBy(GetPermissions(authContext))
 }
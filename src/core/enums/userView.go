@@ -0,0 +1,15 @@
+package enums
+
+type UserView int
+
+const (
+	UserViewPublic UserView = iota
+	UserViewFull
+)
+
+func (view UserView) String() string {
+	return [...]string{
+		"public",
+		"full",
+	}[view]
+}
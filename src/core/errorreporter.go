@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	ErrorKindPanic     = "panic"
+	ErrorKindUnhandled = "unhandled"
+)
+
+// Returned to clients in place of the underlying error, which is only logged and reported.
+const unhandledErrorMessage = "An unexpected error occurred. Please contact support with the error id."
+
+// ErrorReport describes a panic or unhandled error, identified by the error id returned to the client.
+type ErrorReport struct {
+	ErrorId       string                 `json:"errorId"`
+	Kind          string                 `json:"kind"`
+	Message       string                 `json:"message"`
+	Stack         string                 `json:"stack,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	CorrelationId string                 `json:"correlationId,omitempty"`
+	Request       map[string]interface{} `json:"request,omitempty"`
+}
+
+// ErrorReporter sends error reports to an error tracking service.
+type ErrorReporter interface {
+	Report(ctx context.Context, report ErrorReport) error
+}
+
+// FileErrorReporter appends reports as JSON lines to a local file, standing in for an error
+// tracking service during development and testing.
+type FileErrorReporter struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func CreateFileErrorReporter(path string) *FileErrorReporter {
+	return &FileErrorReporter{path: path}
+}
+
+func (reporter *FileErrorReporter) Report(ctx context.Context, report ErrorReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("unable to serialize error report: %v", err.Error())
+	}
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	file, err := os.OpenFile(reporter.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open error report file: %v", err.Error())
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("unable to write error report: %v", err.Error())
+	}
+
+	return nil
+}
+
+// loadErrorReporter returns a file reporter when ERROR_REPORT_FILE is set, otherwise errors are only logged.
+func loadErrorReporter(path string) ErrorReporter {
+	if len(path) == 0 {
+		return nil
+	}
+
+	return CreateFileErrorReporter(path)
+}
+
+func ContextWithErrorReporter(ctx context.Context, reporter ErrorReporter, request map[string]interface{}) context.Context {
+	ctx = context.WithValue(ctx, errorReporterContextKey, reporter)
+	return context.WithValue(ctx, errorRequestContextKey, request)
+}
+
+// ReportError logs the error with its stack trace and the request context under a new error id,
+// passes it to the request's error reporter, and returns the generic error sent to the client.
+func ReportError(ctx context.Context, kind string, message string, stack string) cfe.ResponseError {
+	request, _ := ctx.Value(errorRequestContextKey).(map[string]interface{})
+
+	report := ErrorReport{
+		ErrorId:       ulid.Make().String(),
+		Kind:          kind,
+		Message:       message,
+		Stack:         stack,
+		Timestamp:     time.Now().UTC(),
+		CorrelationId: CorrelationIdFromContext(ctx),
+		Request:       request,
+	}
+
+	logger := slog.Default()
+	logger.Error("Unhandled Error", "ErrorId", report.ErrorId, "Kind", kind, "Error", message, "Stack", stack, "Request", request)
+
+	MetricsFromContext(ctx).IncrementCounter("UnhandledErrors", MetricDimension{Name: "Kind", Value: kind})
+
+	if reporter, ok := ctx.Value(errorReporterContextKey).(ErrorReporter); ok && reporter != nil {
+		err := reporter.Report(ctx, report)
+		if err != nil {
+			logger.Error("Unable to report error", "ErrorId", report.ErrorId, "Error", err.Error())
+		}
+	}
+
+	respError := cfe.ErrorUnhandled(unhandledErrorMessage)
+	respError.ErrorId = report.ErrorId
+
+	return respError
+}
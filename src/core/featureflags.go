@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	cfconfig "cf-user/core/config"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+const (
+	FlagStrictDecoding = "strict-decoding"
+	FlagReadOnly       = "read-only"
+)
+
+// FeatureFlag switches a behaviour on for a percentage of requesters. A nil percentage
+// enables the flag for everyone, including callers without a requesterOid.
+type FeatureFlag struct {
+	Enabled    bool     `json:"enabled"`
+	Percentage *float64 `json:"percentage,omitempty"`
+}
+
+// FlagProvider returns the current flags keyed by name, e.g. {"strict-decoding": {"enabled": true, "percentage": 25}}.
+type FlagProvider interface {
+	Flags(ctx context.Context) (map[string]FeatureFlag, error)
+}
+
+// FeatureFlags evaluates flags from a provider, falling back to the caller's default when the
+// flag is unknown or the provider fails. A nil client always returns the default.
+type FeatureFlags struct {
+	provider FlagProvider
+}
+
+func CreateFeatureFlags(provider FlagProvider) *FeatureFlags {
+	return &FeatureFlags{provider: provider}
+}
+
+// IsEnabled evaluates the flag for the requester in the context and logs the outcome.
+func (flags *FeatureFlags) IsEnabled(ctx context.Context, name string, defaultValue bool) bool {
+	enabled, reason := flags.evaluate(ctx, name, defaultValue)
+
+	slog.Default().Info("Feature Flag", "Flag", name, "Enabled", enabled, "Reason", reason)
+
+	return enabled
+}
+
+func (flags *FeatureFlags) evaluate(ctx context.Context, name string, defaultValue bool) (bool, string) {
+	if flags == nil || flags.provider == nil {
+		return defaultValue, "default"
+	}
+
+	current, err := flags.provider.Flags(ctx)
+	if err != nil {
+		slog.Default().Warn("Unable to load feature flags", "Error", err.Error())
+		return defaultValue, "error"
+	}
+
+	flag, ok := current[name]
+	if !ok {
+		return defaultValue, "default"
+	}
+	if !flag.Enabled {
+		return false, "disabled"
+	}
+	if flag.Percentage == nil {
+		return true, "enabled"
+	}
+
+	return IsInRollout(name, RequesterOidFromContext(ctx), *flag.Percentage), "rollout"
+}
+
+// IsInRollout places the requester in a stable bucket per flag, so a requester keeps the same
+// answer as the percentage grows, and different flags roll out to different requesters.
+// Requests without a requesterOid are only included once the rollout is complete.
+func IsInRollout(name string, requesterOid string, percentage float64) bool {
+	if percentage >= 100 {
+		return true
+	}
+	if percentage <= 0 || len(requesterOid) == 0 {
+		return false
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name + ":" + requesterOid))
+	bucket := hash.Sum32() % 10000
+
+	return float64(bucket) < percentage*100
+}
+
+// FileFlagProvider reads flags from a local JSON file on every evaluation, for tests and local runs.
+type FileFlagProvider struct {
+	Path string
+}
+
+func (provider FileFlagProvider) Flags(ctx context.Context) (map[string]FeatureFlag, error) {
+	content, err := os.ReadFile(provider.Path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read feature flags file: %v", err.Error())
+	}
+
+	return parseFeatureFlags(content)
+}
+
+// ParameterClient is the part of the SSM client the flag provider uses.
+type ParameterClient interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SsmFlagProvider reads flags from a JSON SSM parameter, caching them for the TTL so a warm
+// function picks up changes without redeploying. Stale flags are kept when a refresh fails.
+type SsmFlagProvider struct {
+	name      string
+	ttl       time.Duration
+	client    ParameterClient
+	mutex     sync.Mutex
+	flags     map[string]FeatureFlag
+	expiresAt time.Time
+}
+
+// CreateSsmFlagProvider creates a provider for the named parameter. The client is created from
+// the default AWS config on first use when nil.
+func CreateSsmFlagProvider(client ParameterClient, name string, ttl time.Duration) *SsmFlagProvider {
+	return &SsmFlagProvider{
+		name:   name,
+		ttl:    ttl,
+		client: client,
+	}
+}
+
+func (provider *SsmFlagProvider) Flags(ctx context.Context) (map[string]FeatureFlag, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if provider.flags != nil && time.Now().Before(provider.expiresAt) {
+		return provider.flags, nil
+	}
+
+	flags, err := provider.fetch(ctx)
+	if err != nil {
+		if provider.flags != nil {
+			slog.Default().Warn("Unable to refresh feature flags", "Error", err.Error())
+			return provider.flags, nil
+		}
+
+		return nil, err
+	}
+
+	provider.flags = flags
+	provider.expiresAt = time.Now().Add(provider.ttl)
+
+	return flags, nil
+}
+
+func (provider *SsmFlagProvider) fetch(ctx context.Context) (map[string]FeatureFlag, error) {
+	if provider.client == nil {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %v", err.Error())
+		}
+
+		provider.client = ssm.NewFromConfig(cfg)
+	}
+
+	output, err := provider.client.GetParameter(ctx, &ssm.GetParameterInput{Name: &provider.name})
+	if err != nil {
+		// No parameter means no flags are set, so every evaluation uses its default
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return map[string]FeatureFlag{}, nil
+		}
+
+		return nil, fmt.Errorf("unable to fetch feature flags: %v", err.Error())
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return map[string]FeatureFlag{}, nil
+	}
+
+	return parseFeatureFlags([]byte(*output.Parameter.Value))
+}
+
+func parseFeatureFlags(content []byte) (map[string]FeatureFlag, error) {
+	flags := make(map[string]FeatureFlag)
+	err := json.Unmarshal(content, &flags)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse feature flags: %v", err.Error())
+	}
+
+	return flags, nil
+}
+
+// loadFeatureFlags uses the FEATURE_FLAGS_FILE setting when present, then FEATURE_FLAGS_PARAMETER,
+// otherwise every flag evaluates to its default.
+func loadFeatureFlags(settings *cfconfig.Config) *FeatureFlags {
+	if len(settings.FeatureFlagsFile) > 0 {
+		return CreateFeatureFlags(FileFlagProvider{Path: settings.FeatureFlagsFile})
+	}
+	if len(settings.FeatureFlagsParameter) > 0 {
+		return CreateFeatureFlags(CreateSsmFlagProvider(nil, settings.FeatureFlagsParameter, settings.FeatureFlagsTtl))
+	}
+
+	return CreateFeatureFlags(nil)
+}
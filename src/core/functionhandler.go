@@ -7,8 +7,11 @@ import (
 	"log"
 	"log/slog"
 	"os"
-	"strings"
+	"reflect"
+	"runtime/debug"
+	"time"
 
+	cfconfig "cf-user/core/config"
 	cfe "cf-user/core/enums"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -16,9 +19,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/go-playground/validator"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type LambdaConfig[TRequest interface{}, TResponse interface{}] struct {
+	Config          *cfconfig.Config
+	FeatureFlags    *FeatureFlags
 	DynamoDbStore   *DynamoDbStore
 	FunctionHandler *FunctionHandler[TRequest, TResponse]
 }
@@ -27,16 +33,68 @@ type FunctionHandler[TRequest interface{}, TResponse interface{}] struct {
 	service      string
 	stage        string
 	roleRequired cfe.LambdaRole
+	requirement  cfe.AuthRequirement
+	roleOptional bool
+	ownerCheck   OwnershipCheck
+	unchanged    UnchangedFieldsCheck[TRequest]
+	cachePolicy  CachePolicy[TResponse]
 	coldstart    bool
 	Validate     *validator.Validate
+	Decoding     DecodeOptions
+	Cors         *CorsPolicy
+	Reporter     ErrorReporter
+	Flags        *FeatureFlags
+	Redactor     *Redactor
+	LogLevel     slog.Level
+	DebugSample  float64
 	Logger       *slog.Logger
+	Metrics      *Metrics
+	namespace    string
+	middleware   []Middleware
+}
+
+type RequestHandlerFunc func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse
+
+// Middleware wraps authorization, validation and the handler callback. Middleware registered
+// first is the outermost, and runs once logging, metrics and tracing are set up for the request.
+type Middleware func(next RequestHandlerFunc) RequestHandlerFunc
+
+type requestIdentity struct {
+	AccountID                     string `json:"accountId"`
+	APIKeyID                      string `json:"apiKeyId"`
+	APIKey                        string `json:"apiKey" log:"redact"`
+	AccessKey                     string `json:"accessKey" log:"redact"`
+	Caller                        string `json:"caller" log:"mask"`
+	User                          string `json:"user" log:"mask"`
+	UserArn                       string `json:"userArn" log:"mask"`
+	CognitoIdentityPoolID         string `json:"cognitoIdentityPoolId"`
+	CognitoIdentityID             string `json:"cognitoIdentityId" log:"mask"`
+	CognitoAuthenticationType     string `json:"cognitoAuthenticationType"`
+	CognitoAuthenticationProvider string `json:"cognitoAuthenticationProvider" log:"mask"`
+	SourceIP                      string `json:"sourceIp" log:"mask"`
+	UserAgent                     string `json:"userAgent"`
 }
 
 func CreateLambaConfig[TRequest interface{}, TResponse interface{}](roleRequired cfe.LambdaRole, ddbStore *DynamoDbStore) *LambdaConfig[TRequest, TResponse] {
 	lambdaConfig := LambdaConfig[TRequest, TResponse]{}
 
-	service := os.Getenv("SERVICE")
-	stage := os.Getenv("STAGE")
+	settings := cfconfig.Current()
+	lambdaConfig.Config = settings
+
+	service := settings.Service
+	stage := settings.Stage
+
+	redactionPolicy, err := cfe.GetRedactionPolicy(&settings.LogRedactionPolicy)
+	if err != nil {
+		log.Panicf("Unable to load log redaction policy, %v", err.Error())
+	}
+
+	err = InitTracing(service, stage, settings.TracesExporter, settings.TracesEndpoint)
+	if err != nil {
+		log.Panicf("Unable to initialize tracing, %v", err.Error())
+	}
+
+	lambdaConfig.FeatureFlags = loadFeatureFlags(settings)
 
 	if ddbStore != nil {
 		lambdaConfig.DynamoDbStore = ddbStore
@@ -54,33 +112,112 @@ func CreateLambaConfig[TRequest interface{}, TResponse interface{}](roleRequired
 		service:      service,
 		stage:        stage,
 		roleRequired: roleRequired,
+		requirement:  roleRequired,
 		coldstart:    true,
 		Validate:     validator.New(),
+		Redactor:     CreateRedactor(*redactionPolicy),
+		LogLevel:     settings.LogLevel,
+		DebugSample:  settings.LogDebugSampleRate,
+		Reporter:     loadErrorReporter(settings.ErrorReportFile),
+		Flags:        lambdaConfig.FeatureFlags,
 		Logger:       nil,
+		Metrics:      nil,
+		namespace:    settings.MetricsNamespace,
+	}
+
+	corsPolicy, err := GetCorsPolicy(stage, settings.CorsAllowedOrigins)
+	if err != nil {
+		log.Panicf("Unable to load cors policy, %v", err.Error())
+	}
+	lambdaConfig.FunctionHandler.Cors = corsPolicy
+
+	rateLimit, err := GetRateLimit(roleRequired, settings.RateLimits)
+	if err != nil {
+		log.Panicf("Unable to load rate limits, %v", err.Error())
 	}
 
+	lambdaConfig.FunctionHandler.Use(ContentNegotiationMiddleware(DefaultCompressionMinBytes))
+	lambdaConfig.FunctionHandler.Use(MaintenanceMiddleware(roleRequired, settings.ReadOnly, settings.MaintenanceRetryAfter, lambdaConfig.FeatureFlags))
+	lambdaConfig.FunctionHandler.Use(RateLimitMiddleware(lambdaConfig.DynamoDbStore, roleRequired, *rateLimit))
+
 	return &lambdaConfig
 }
 
-func (handler *FunctionHandler[TRequest, TResponse]) HandleRequest(apiRequest events.APIGatewayProxyRequest, callback func(req TRequest) (*TResponse, *cfe.ResponseError)) (handlerResponse events.APIGatewayProxyResponse) {
+func (handler *FunctionHandler[TRequest, TResponse]) HandleRequest(ctx context.Context, apiRequest events.APIGatewayProxyRequest, callback func(ctx context.Context, req TRequest) (*TResponse, *cfe.ResponseError)) (handlerResponse events.APIGatewayProxyResponse) {
+	startTime := time.Now()
+
 	defer func() {
 		handler.coldstart = false
 	}()
 
+	handler.Metrics = CreateMetrics(handler.namespace, os.Stdout,
+		MetricDimension{Name: "Service", Value: handler.service},
+		MetricDimension{Name: "Stage", Value: handler.stage},
+		MetricDimension{Name: "FunctionName", Value: lambdacontext.FunctionName},
+	)
+	correlationId := ResolveCorrelationId(apiRequest)
+
+	handler.Metrics.SetProperty("FunctionRequestId", apiRequest.RequestContext.RequestID)
+	handler.Metrics.SetProperty("CorrelationId", correlationId)
+	if handler.coldstart {
+		handler.Metrics.IncrementCounter("ColdStart")
+	}
+
+	ctx = ContextWithMetrics(ctx, handler.Metrics)
+	ctx = ContextWithCorrelationId(ctx, correlationId)
+	ctx = ContextWithRequesterOid(ctx, GetRequesterOid(apiRequest))
+
+	capacityTracker := CreateCapacityTracker()
+	ctx = ContextWithCapacityTracker(ctx, capacityTracker)
+
+	ctx, span := startHandlerSpan(ContextWithTraceParent(ctx, apiRequest), apiRequest, handler.coldstart)
+	span.SetAttributes(attribute.String("cf.correlation_id", correlationId))
+
+	defer func() {
+		endHandlerSpan(span, handlerResponse)
+
+		err := FlushTracing(ctx)
+		if err != nil && handler.Logger != nil {
+			handler.Logger.Error("Unable to flush traces", "Error", err.Error())
+		}
+	}()
+
+	defer func() {
+		handler.recordResponseMetrics(handlerResponse, startTime)
+		capacityTracker.Report(handler.Logger, handler.Metrics, RequesterOidFromContext(ctx))
+
+		err := handler.Metrics.Flush()
+		if err != nil && handler.Logger != nil {
+			handler.Logger.Error("Unable to flush metrics", "Error", err.Error())
+		}
+	}()
+
+	// Runs after panics are recovered, so every response carries the correlation id, consumed capacity and CORS headers
+	defer func() {
+		SetResponseHeader(&handlerResponse, CorrelationIdHeader, correlationId)
+		if handler.stage != "prod" {
+			SetResponseHeader(&handlerResponse, ConsumedCapacityHeader, capacityTracker.HeaderValue())
+		}
+
+		if handler.Cors != nil {
+			handler.Cors.Apply(apiRequest, &handlerResponse)
+		}
+	}()
+
 	defer func() {
 		if r := recover(); r != nil {
-			var respError cfe.ResponseError
+			var message string
 
 			switch rVal := r.(type) {
 			case string:
-				respError = cfe.ErrorUnhandled(rVal)
+				message = rVal
 			case error:
-				respError = cfe.ErrorUnhandled(rVal.Error())
+				message = rVal.Error()
 			default:
-				respError = cfe.ErrorUnhandled(fmt.Sprint(rVal))
+				message = fmt.Sprint(rVal)
 			}
 
-			handlerResponse = respError.ApiResponse()
+			handlerResponse = ErrorResponse(ctx, ReportError(ctx, ErrorKindPanic, message, string(debug.Stack())))
 		}
 	}()
 
@@ -93,10 +230,19 @@ func (handler *FunctionHandler[TRequest, TResponse]) HandleRequest(apiRequest ev
 	logAttr = append(logAttr, slog.Int("FunctionMemoryLimitInMB", lambdacontext.MemoryLimitInMB))
 	logAttr = append(logAttr, slog.Bool("ColdStart", handler.coldstart))
 	logAttr = append(logAttr, slog.String("XRayTraceId", traceId))
+	logAttr = append(logAttr, slog.String("TraceId", span.SpanContext().TraceID().String()))
+	logAttr = append(logAttr, slog.String("CorrelationId", correlationId))
 	logAttr = append(logAttr, slog.String("Service", handler.service))
 	logAttr = append(logAttr, slog.String("Stage", handler.stage))
 
-	handler.Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil).WithAttrs(logAttr))
+	logLevel, debugReason := ResolveLogLevel(apiRequest, handler.LogLevel, handler.DebugSample)
+	logAttr = append(logAttr, slog.String("LogLevel", logLevel.String()))
+	if len(debugReason) > 0 {
+		logAttr = append(logAttr, slog.String("DebugReason", debugReason))
+		handler.Metrics.IncrementCounter("DebugLogged", MetricDimension{Name: "Reason", Value: debugReason})
+	}
+
+	handler.Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}).WithAttrs(logAttr))
 	slog.SetDefault(handler.Logger)
 
 	reqContext := make(map[string]interface{})
@@ -104,68 +250,277 @@ func (handler *FunctionHandler[TRequest, TResponse]) HandleRequest(apiRequest ev
 	reqContext["Stage"] = apiRequest.RequestContext.Stage
 	reqContext["DomainName"] = apiRequest.RequestContext.DomainName
 	reqContext["RequestID"] = apiRequest.RequestContext.RequestID
-	reqContext["Identity"] = apiRequest.RequestContext.Identity
+	reqContext["Identity"] = handler.Redactor.Sanitize(sanitizableIdentity(apiRequest.RequestContext.Identity))
 	reqContext["ResourcePath"] = apiRequest.RequestContext.ResourcePath
 	reqContext["Path"] = apiRequest.RequestContext.Path
 	reqContext["HTTPMethod"] = apiRequest.RequestContext.HTTPMethod
 	reqContext["RequestTime"] = apiRequest.RequestContext.RequestTime
-	reqContext["Body"] = apiRequest.Body
+	reqContext["RedactionPolicy"] = handler.Redactor.Policy().String()
 
 	handler.Logger.Info("Properties", "Request", reqContext)
 
-	validRole := handler.roleRequired.ExistsInAuthContext(apiRequest.RequestContext.Authorizer)
-	if !validRole {
-		return cfe.ErrorAuthorization("You do not have the appropriate permissions to perform this action. Please check the appropriate documentation to ensure you have the correct permissions.").ApiResponse()
+	ctx = ContextWithErrorReporter(ctx, handler.Reporter, reqContext)
+	ctx = ContextWithRedactor(ctx, handler.Redactor)
+
+	var next RequestHandlerFunc = func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return handler.processRequest(ctx, apiRequest, callback)
+	}
+	for i := len(handler.middleware) - 1; i >= 0; i-- {
+		next = handler.middleware[i](next)
 	}
 
-	requestMethod := apiRequest.HTTPMethod
+	return next(ctx, apiRequest)
+}
 
-	var requestValue TRequest
+// ColdStart reports whether the request being handled is the first since the function started.
+func (handler *FunctionHandler[TRequest, TResponse]) ColdStart() bool {
+	return handler.coldstart
+}
 
-	if !(requestMethod == "GET" || requestMethod == "DELETE") {
-		requestBody := strings.TrimSpace(apiRequest.Body)
-		if len(requestBody) <= 0 {
-			return cfe.ErrorValidation("Body was null or empty.").ApiResponse()
-		}
+func (handler *FunctionHandler[TRequest, TResponse]) Use(middleware ...Middleware) {
+	handler.middleware = append(handler.middleware, middleware...)
+}
+
+// RequireAuth replaces the required role with a combination of permissions, e.g. cfe.AnyOf(...).
+func (handler *FunctionHandler[TRequest, TResponse]) RequireAuth(requirement cfe.AuthRequirement) {
+	handler.requirement = requirement
+}
+
+// AllowWithoutRole lets callers without the required role through, for handlers that
+// limit what they return to those callers rather than rejecting the request.
+func (handler *FunctionHandler[TRequest, TResponse]) AllowWithoutRole() {
+	handler.roleOptional = true
+}
+
+// AllowOwner lets callers without the required role through when they own the requested resource.
+func (handler *FunctionHandler[TRequest, TResponse]) AllowOwner(ownerCheck OwnershipCheck) {
+	handler.ownerCheck = ownerCheck
+}
 
-		err := json.Unmarshal([]byte(requestBody), &requestValue)
+// ClearUnchangedWith clears request fields that would not change the resource before write permissions are checked.
+func (handler *FunctionHandler[TRequest, TResponse]) ClearUnchangedWith(check UnchangedFieldsCheck[TRequest]) {
+	handler.unchanged = check
+}
+
+// CacheWith sets the Cache-Control header of successful responses using the given policy.
+func (handler *FunctionHandler[TRequest, TResponse]) CacheWith(policy CachePolicy[TResponse]) {
+	handler.cachePolicy = policy
+}
+
+func (handler *FunctionHandler[TRequest, TResponse]) processRequest(ctx context.Context, apiRequest events.APIGatewayProxyRequest, callback func(ctx context.Context, req TRequest) (*TResponse, *cfe.ResponseError)) events.APIGatewayProxyResponse {
+	authCtx, authSpan := StartSpan(ctx, "Authorize", attribute.String("cf.role_required", handler.requirement.String()))
+	permissions := cfe.GetPermissions(apiRequest.RequestContext.Authorizer)
+	validRole := handler.requirement.IsSatisfiedBy(permissions)
+
+	if !validRole && handler.ownerCheck != nil {
+		isOwner, err := handler.ownerCheck(authCtx, apiRequest)
 		if err != nil {
-			return cfe.ErrorValidation("Body contains invalid payload.").ApiResponse()
+			EndSpan(authSpan, err)
+			return ErrorResponse(ctx, cfe.ErrorGetOrDefault(err))
 		}
 
-		err = handler.Validate.Struct(requestValue)
-		if err != nil {
-			e := cfe.ErrorValidation("Request failed validation.")
-			for _, err := range err.(validator.ValidationErrors) {
-				e.AddData(fmt.Sprintf("Field: %v (%v %v)", err.Field(), err.Tag(), err.Param()))
+		authSpan.SetAttributes(attribute.Bool("cf.owner", isOwner))
+		validRole = isOwner
+	}
+
+	authSpan.SetAttributes(attribute.Bool("cf.authorized", validRole))
+	authSpan.End()
+
+	if !validRole && !handler.roleOptional {
+		return ErrorResponse(ctx, cfe.ErrorAuthorization("You do not have the appropriate permissions to perform this action. Please check the appropriate documentation to ensure you have the correct permissions."))
+	}
+
+	_, validationSpan := StartSpan(ctx, "Validate")
+	requestValue, validationError := handler.decodeRequest(ctx, apiRequest)
+	if validationError != nil {
+		EndSpan(validationSpan, validationError)
+		return ErrorResponse(ctx, *validationError)
+	}
+	validationSpan.End()
+
+	if HasWritePolicy(reflect.TypeOf(requestValue)) {
+		if handler.unchanged != nil {
+			err := handler.unchanged(ctx, &requestValue)
+			if err != nil {
+				return ErrorResponse(ctx, cfe.ErrorGetOrDefault(err))
+			}
+		}
+
+		permissionErrors := CheckWritePermissions(requestValue, permissions)
+		if permissionErrors != nil {
+			e := cfe.ErrorAuthorization("You do not have the appropriate permissions to modify one or more fields.")
+			for _, err := range permissionErrors {
+				e.AddData(err.Error())
+				e.AddFieldError(err.Field, "forbidden", fmt.Sprintf("requires the %v permission", err.Permission))
 			}
 
-			return e.ApiResponse()
+			return ErrorResponse(ctx, e)
 		}
 	}
 
-	response, respError := callback(requestValue)
+	response, respError := callback(ctx, requestValue)
 	if respError != nil {
-		return respError.ApiResponse()
+		return ErrorResponse(ctx, *respError)
 	}
 
+	handler.Logger.Info("Response", "Body", handler.Redactor.Sanitize(response))
+
 	if responseSuccess, ok := interface{}(*response).(bool); ok {
 		if responseSuccess {
 			return events.APIGatewayProxyResponse{
 				StatusCode: 204,
 			}
 		} else {
-			return cfe.ErrorUnhandled("Request failed.").ApiResponse()
+			return ErrorResponse(ctx, cfe.ErrorUnhandled("Request failed."))
 		}
 	}
 
 	val, err := json.Marshal(&response)
 	if err != nil {
-		return cfe.ErrorUnhandled("Unable to serialize response payload.").ApiResponse()
+		return ErrorResponse(ctx, cfe.ErrorUnhandled("Unable to serialize response payload."))
 	}
 
-	return events.APIGatewayProxyResponse{
+	apiResponse := events.APIGatewayProxyResponse{
 		StatusCode: 200,
 		Body:       string(val),
 	}
+
+	if lastModifier, ok := interface{}(response).(LastModifier); ok && !lastModifier.LastModified().IsZero() {
+		SetResponseHeader(&apiResponse, LastModifiedHeader, FormatHttpDate(lastModifier.LastModified()))
+	}
+
+	if handler.cachePolicy != nil {
+		if cacheControl := handler.cachePolicy(ctx, response); len(cacheControl) > 0 {
+			SetResponseHeader(&apiResponse, CacheControlHeader, cacheControl)
+		}
+	}
+
+	return apiResponse
+}
+
+// decodeOptions applies strict decoding to every handler while the strict-decoding flag is on.
+func (handler *FunctionHandler[TRequest, TResponse]) decodeOptions(ctx context.Context) DecodeOptions {
+	if handler.Decoding != StrictDecodeOptions() && handler.Flags.IsEnabled(ctx, FlagStrictDecoding, false) {
+		return StrictDecodeOptions()
+	}
+
+	return handler.Decoding
+}
+
+func (handler *FunctionHandler[TRequest, TResponse]) decodeRequest(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (TRequest, *cfe.ResponseError) {
+	requestMethod := apiRequest.HTTPMethod
+	hasBody := !(requestMethod == "GET" || requestMethod == "DELETE")
+
+	var requestValue TRequest
+
+	if hasBody {
+		options := handler.decodeOptions(ctx)
+
+		requestBody, bodyError := ReadRequestBody(apiRequest, options)
+		if bodyError != nil {
+			return requestValue, bodyError
+		}
+		if len(requestBody) <= 0 {
+			e := cfe.ErrorValidation("Body was null or empty.")
+			return requestValue, &e
+		}
+
+		decodingError := DecodeJson(requestBody, options, &requestValue)
+		if decodingError != nil {
+			e := cfe.ErrorValidation("Body contains invalid payload.")
+			e.AddData(decodingError.Error())
+			e.AddFieldError(decodingError.Field, decodingError.Code, decodingError.Message)
+			return requestValue, &e
+		}
+	}
+
+	requestType := reflect.TypeOf(&requestValue).Elem()
+	if HasBindings(requestType) {
+		bindingErrors := BindRequest(apiRequest, &requestValue)
+		if bindingErrors != nil {
+			e := cfe.ErrorValidation("Request contains invalid parameters.")
+			for _, err := range bindingErrors {
+				e.AddData(fmt.Sprintf("Field: %v (%v)", err.Field, err.Error()))
+				e.AddFieldError(err.Name, err.Code, err.Reason)
+			}
+
+			return requestValue, &e
+		}
+	} else if !hasBody {
+		return requestValue, nil
+	}
+
+	err := handler.Validate.Struct(requestValue)
+	if err != nil {
+		e := cfe.ErrorValidation("Request failed validation.")
+		for _, err := range err.(validator.ValidationErrors) {
+			e.AddData(fmt.Sprintf("Field: %v (%v %v)", err.Field(), err.Tag(), err.Param()))
+			e.AddFieldError(jsonFieldPath(requestType, err.StructNamespace()), err.Tag(), validationMessage(err))
+		}
+
+		return requestValue, &e
+	}
+
+	if hasBody {
+		handler.Logger.Info("Request Body", "Body", handler.Redactor.Sanitize(requestValue))
+	} else {
+		handler.Logger.Info("Request Parameters", "Parameters", handler.Redactor.Sanitize(requestValue))
+	}
+
+	return requestValue, nil
+}
+
+// ErrorResponse converts an error to an API response, tagging it with the request correlation id.
+// Unhandled errors are reported first, so the client only receives a generic message and error id.
+func ErrorResponse(ctx context.Context, respError cfe.ResponseError) events.APIGatewayProxyResponse {
+	if respError.ErrorCode == cfe.ErrorCodeUnhandledException.String() && len(respError.ErrorId) == 0 {
+		respError = ReportError(ctx, ErrorKindUnhandled, respError.ErrorMessage, "")
+	}
+
+	respError.CorrelationId = CorrelationIdFromContext(ctx)
+
+	MetricsFromContext(ctx).IncrementCounter("Errors", MetricDimension{Name: "ErrorCode", Value: respError.ErrorCode})
+
+	return respError.ApiResponse()
+}
+
+func (handler *FunctionHandler[TRequest, TResponse]) recordResponseMetrics(response events.APIGatewayProxyResponse, startTime time.Time) {
+	statusClass := response.StatusCode / 100
+
+	for _, class := range []int{2, 4, 5} {
+		value := 0.0
+		if class == statusClass {
+			value = 1
+		}
+
+		handler.Metrics.AddMetric(fmt.Sprintf("Response%vXX", class), cfe.MetricUnitCount, value)
+	}
+
+	handler.Metrics.AddDuration("HandlerLatency", startTime)
+}
+
+func sanitizableIdentity(identity events.APIGatewayRequestIdentity) requestIdentity {
+	return requestIdentity{
+		AccountID:                     identity.AccountID,
+		APIKeyID:                      identity.APIKeyID,
+		APIKey:                        identity.APIKey,
+		AccessKey:                     identity.AccessKey,
+		Caller:                        identity.Caller,
+		User:                          identity.User,
+		UserArn:                       identity.UserArn,
+		CognitoIdentityPoolID:         identity.CognitoIdentityPoolID,
+		CognitoIdentityID:             identity.CognitoIdentityID,
+		CognitoAuthenticationType:     identity.CognitoAuthenticationType,
+		CognitoAuthenticationProvider: identity.CognitoAuthenticationProvider,
+		SourceIP:                      identity.SourceIP,
+		UserAgent:                     identity.UserAgent,
+	}
+}
+
+func validationMessage(err validator.FieldError) string {
+	if len(err.Param()) == 0 {
+		return fmt.Sprintf("failed the %v rule", err.Tag())
+	}
+
+	return fmt.Sprintf("failed the %v=%v rule", err.Tag(), err.Param())
 }
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// GetHeader looks up a request header by name, ignoring the casing used by the client.
+func GetHeader(headers map[string]string, name string) (string, bool) {
+	if value, ok := headers[name]; ok {
+		return value, true
+	}
+
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func SetResponseHeader(response *events.APIGatewayProxyResponse, name string, value string) {
+	if response.Headers == nil {
+		response.Headers = make(map[string]string)
+	}
+
+	response.Headers[name] = value
+}
+
+// AddVaryHeader appends a request header name to the Vary response header, keeping those already listed.
+func AddVaryHeader(response *events.APIGatewayProxyResponse, name string) {
+	existing := response.Headers[VaryHeader]
+	for _, value := range strings.Split(existing, ",") {
+		if strings.EqualFold(strings.TrimSpace(value), name) {
+			return
+		}
+	}
+
+	if len(existing) > 0 {
+		name = existing + ", " + name
+	}
+
+	SetResponseHeader(response, VaryHeader, name)
+}
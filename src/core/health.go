@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MediaTypeHealthJson is the media type of the draft health check response format.
+const MediaTypeHealthJson = "application/health+json"
+
+// HealthVersion is the public major version of the API reported by health checks.
+const HealthVersion = "1"
+
+// BuildVersion identifies the deployed build, set at link time with
+// -ldflags "-X cf-user/core.BuildVersion=<version>".
+var BuildVersion = "dev"
+
+const (
+	// A dependency answering slower than this is reported as warn rather than pass.
+	healthLatencyWarn = time.Second
+	// Deep checks give up on DynamoDB after this, so a hung dependency still gets a fail answer.
+	healthCheckTimeout = 5 * time.Second
+	// Output of a check whose call failed. The error itself is only logged, as it can name the
+	// account, role and table to the anonymous callers of the health endpoints.
+	healthUnreachableOutput = "unreachable"
+)
+
+// HealthProbe is the part of the store probed by deep health checks.
+type HealthProbe interface {
+	TableName() string
+	IndexNames() []string
+	DescribeUserTable(ctx context.Context) (map[string]string, error)
+	ProbeUserTable(ctx context.Context) error
+	ProbeUserIndex(ctx context.Context, indexName string) error
+}
+
+// CreateHealthResponse returns a passing shallow health response for the running build.
+func CreateHealthResponse(service string, stage string, coldStart bool) *cfm.HealthResponse {
+	return &cfm.HealthResponse{
+		Status:      cfe.HealthPass.String(),
+		Version:     HealthVersion,
+		ReleaseId:   BuildVersion,
+		ServiceId:   service,
+		Description: "health of the " + service + " service",
+		Stage:       stage,
+		ColdStart:   coldStart,
+	}
+}
+
+// CheckUserTable describes the user table and reads from it and each of its indexes, reporting the
+// status and latency of each. The returned status is the worst of the checks.
+func CheckUserTable(ctx context.Context, probe HealthProbe) (map[string][]cfm.HealthCheck, cfe.HealthStatus) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := make(map[string][]cfm.HealthCheck)
+	status := cfe.HealthPass
+
+	add := func(name string, check cfm.HealthCheck, checkStatus cfe.HealthStatus) {
+		checks[name] = append(checks[name], check)
+		status = max(status, checkStatus)
+	}
+
+	start := time.Now()
+	statuses, err := probe.DescribeUserTable(ctx)
+	elapsed := time.Since(start)
+
+	components := append([]string{probe.TableName()}, probe.IndexNames()...)
+	for _, component := range components {
+		check, checkStatus := tableStatusCheck(ctx, component, statuses[component], elapsed, err)
+		add("dynamodb:describeTable", check, checkStatus)
+	}
+
+	start = time.Now()
+	err = probe.ProbeUserTable(ctx)
+	check, checkStatus := responseTimeCheck(ctx, probe.TableName(), time.Since(start), err)
+	add("dynamodb:responseTime", check, checkStatus)
+
+	for _, indexName := range probe.IndexNames() {
+		start = time.Now()
+		err = probe.ProbeUserIndex(ctx, indexName)
+		check, checkStatus = responseTimeCheck(ctx, indexName, time.Since(start), err)
+		add("dynamodb:responseTime", check, checkStatus)
+	}
+
+	return checks, status
+}
+
+// HealthMiddleware serves health responses as application/health+json, answering with a 503 when
+// the health check failed so load balancers and monitors need not parse the body.
+func HealthMiddleware() Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			response := next(ctx, apiRequest)
+			if response.StatusCode != http.StatusOK {
+				return response
+			}
+
+			var health cfm.HealthResponse
+			if err := json.Unmarshal([]byte(response.Body), &health); err != nil {
+				return response
+			}
+
+			SetResponseHeader(&response, ContentTypeHeader, MediaTypeHealthJson)
+			if health.Status == cfe.HealthFail.String() {
+				response.StatusCode = http.StatusServiceUnavailable
+			}
+
+			MetricsFromContext(ctx).IncrementCounter("HealthCheck", MetricDimension{Name: "Status", Value: health.Status})
+
+			return response
+		}
+	}
+}
+
+func tableStatusCheck(ctx context.Context, component string, tableStatus string, elapsed time.Duration, err error) (cfm.HealthCheck, cfe.HealthStatus) {
+	check := healthCheck(component, elapsed)
+
+	status := cfe.HealthPass
+	switch {
+	case err != nil:
+		status = cfe.HealthFail
+		check.Output = unreachable(ctx, component, err)
+	case len(tableStatus) == 0:
+		status = cfe.HealthFail
+		check.Output = "not found"
+	case tableStatus == "UPDATING":
+		status = cfe.HealthWarn
+		check.Output = tableStatus
+	case tableStatus != "ACTIVE":
+		status = cfe.HealthFail
+		check.Output = tableStatus
+	}
+
+	check.Status = status.String()
+	return check, status
+}
+
+func responseTimeCheck(ctx context.Context, component string, elapsed time.Duration, err error) (cfm.HealthCheck, cfe.HealthStatus) {
+	check := healthCheck(component, elapsed)
+
+	status := cfe.HealthPass
+	if err != nil {
+		status = cfe.HealthFail
+		check.Output = unreachable(ctx, component, err)
+	} else if elapsed > healthLatencyWarn {
+		status = cfe.HealthWarn
+		check.Output = "slow response"
+	}
+
+	check.Status = status.String()
+	return check, status
+}
+
+func healthCheck(component string, elapsed time.Duration) cfm.HealthCheck {
+	return cfm.HealthCheck{
+		ComponentId:   component,
+		ComponentType: "datastore",
+		ObservedValue: float64(elapsed.Microseconds()) / 1000,
+		ObservedUnit:  "ms",
+		Time:          time.Now().UTC(),
+	}
+}
+
+// unreachable logs a failed check's error, which carries the request's correlation id, and returns the fixed output.
+func unreachable(ctx context.Context, component string, err error) string {
+	slog.Default().ErrorContext(ctx, "Health check failed", "Component", component, "Error", err.Error())
+
+	return healthUnreachableOutput
+}
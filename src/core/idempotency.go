@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// Matches the Lambda timeout, after which an in progress attempt can no longer complete.
+const idempotencyLockDuration = 30 * time.Second
+
+// IdempotencyMiddleware lets callers safely retry a request by sending an Idempotency-Key header.
+// The first successful response for a key is stored for the given ttl and replayed for identical
+// retries. Errors are not stored, so a retry after a corrected request or a granted role runs again.
+func IdempotencyMiddleware(ddbStore *DynamoDbStore, ttl time.Duration) Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			idempotencyKey, ok := GetHeader(apiRequest.Headers, IdempotencyKeyHeader)
+			idempotencyKey = strings.TrimSpace(idempotencyKey)
+			if !ok || len(idempotencyKey) == 0 || DryRunFromContext(ctx) {
+				return next(ctx, apiRequest)
+			}
+
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				return ErrorResponse(ctx, cfe.ErrorValidation(fmt.Sprintf("%v header must not exceed %v characters.", IdempotencyKeyHeader, maxIdempotencyKeyLength)))
+			}
+
+			now := time.Now().UTC()
+			requesterOid := GetRequesterOid(apiRequest)
+
+			record := &cfm.IdempotencyRecord{
+				PK:            fmt.Sprintf("IDEMPOTENCY#%v#%v", requesterOid, idempotencyKey),
+				SK:            fmt.Sprintf("REQUEST#%v#%v", apiRequest.HTTPMethod, apiRequest.Resource),
+				RequestHash:   hashIdempotentRequest(apiRequest),
+				LockExpiresAt: now.Add(idempotencyLockDuration).Unix(),
+				ExpiresAt:     now.Add(ttl).Unix(),
+			}
+
+			existing, err := ddbStore.StartIdempotentRequest(ctx, record)
+			if err != nil {
+				return ErrorResponse(ctx, cfe.ErrorGetOrDefault(err))
+			}
+
+			if existing != nil {
+				return replayIdempotentRequest(ctx, record, existing)
+			}
+
+			response := next(ctx, apiRequest)
+
+			if response.StatusCode < 200 || response.StatusCode >= 300 {
+				// Let the client retry failed attempts with the same key
+				err = ddbStore.DeleteIdempotentRequest(ctx, record)
+				if err != nil {
+					slog.Default().Error("Unable to release idempotency key", "Error", err.Error())
+				}
+
+				return response
+			}
+
+			record.StatusCode = response.StatusCode
+			record.ResponseBody = response.Body
+			record.ResponseHeaders = response.Headers
+			record.IsBase64Encoded = response.IsBase64Encoded
+
+			err = ddbStore.CompleteIdempotentRequest(ctx, record)
+			if err != nil {
+				slog.Default().Error("Unable to store idempotent response", "Error", err.Error())
+			}
+
+			return response
+		}
+	}
+}
+
+func replayIdempotentRequest(ctx context.Context, record *cfm.IdempotencyRecord, existing *cfm.IdempotencyRecord) events.APIGatewayProxyResponse {
+	if existing.RequestHash != record.RequestHash {
+		return ErrorResponse(ctx, cfe.ErrorConflict(cfe.ErrorCodeIdempotencyKeyReused, fmt.Sprintf("%v was already used for a different request.", IdempotencyKeyHeader)))
+	}
+
+	if existing.Status == cfe.IdempotencyInProgress.String() {
+		response := ErrorResponse(ctx, cfe.ErrorConflict(cfe.ErrorCodeRequestInProgress, fmt.Sprintf("A request with the same %v is still being processed.", IdempotencyKeyHeader)))
+		SetResponseHeader(&response, "Retry-After", "1")
+
+		return response
+	}
+
+	slog.Default().Info("Replaying idempotent response", "StatusCode", existing.StatusCode)
+
+	response := events.APIGatewayProxyResponse{
+		StatusCode:      existing.StatusCode,
+		Body:            existing.ResponseBody,
+		IsBase64Encoded: existing.IsBase64Encoded,
+	}
+	for name, value := range existing.ResponseHeaders {
+		SetResponseHeader(&response, name, value)
+	}
+	SetResponseHeader(&response, IdempotentReplayedHeader, "true")
+
+	return response
+}
+
+func hashIdempotentRequest(apiRequest events.APIGatewayProxyRequest) string {
+	hash := sha256.New()
+	hash.Write([]byte(apiRequest.HTTPMethod))
+	hash.Write([]byte{0})
+	hash.Write([]byte(apiRequest.Path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(apiRequest.Body))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
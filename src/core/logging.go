@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"reflect"
+	"strconv"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DebugLogHeader raises the log level of a single request to Debug for callers holding cf:admin:user.
+const DebugLogHeader = "X-Debug-Log"
+
+const (
+	DebugReasonHeader  = "header"
+	DebugReasonSampled = "sampled"
+)
+
+// Attributes that never hold personal data, so DynamoDB debug logs show them as is. Every other
+// attribute value, including keys and expression values built from emails or subjects, is masked.
+var debugSafeAttributes = map[string]bool{
+	"UserId":        true,
+	"Username":      true,
+	"AccountType":   true,
+	"CreatedDate":   true,
+	"UpdatedDate":   true,
+	"Status":        true,
+	"StatusCode":    true,
+	"RequestHash":   true,
+	"Tokens":        true,
+	"UpdatedAt":     true,
+	"ExpiresAt":     true,
+	"LockExpiresAt": true,
+}
+
+// Attributes logged with the same tag as their model field, rather than the default mask.
+var debugAttributeTags = map[string]string{
+	"FirstName":  logTagInitial,
+	"LastName":   logTagInitial,
+	"AddressOne": logTagRedact,
+	"AddressTwo": logTagRedact,
+	"PostalCode": logTagRedact,
+}
+
+var attributeValueType = reflect.TypeOf((*types.AttributeValue)(nil)).Elem()
+
+// ResolveLogLevel raises the configured level to Debug when an admin sends the debug header, or
+// for the sampled fraction of requests, returning the reason it was raised.
+func ResolveLogLevel(apiRequest events.APIGatewayProxyRequest, level slog.Level, sampleRate float64) (slog.Level, string) {
+	if level <= slog.LevelDebug {
+		return level, ""
+	}
+
+	if value, ok := GetHeader(apiRequest.Headers, DebugLogHeader); ok {
+		debug, err := strconv.ParseBool(value)
+		if err == nil && debug && cfe.AdminUser.IsSatisfiedBy(cfe.GetPermissions(apiRequest.RequestContext.Authorizer)) {
+			return slog.LevelDebug, DebugReasonHeader
+		}
+	}
+
+	if sampleRate > 0 && rand.Float64() < sampleRate {
+		return slog.LevelDebug, DebugReasonSampled
+	}
+
+	return level, ""
+}
+
+func ContextWithRedactor(ctx context.Context, redactor *Redactor) context.Context {
+	return context.WithValue(ctx, redactorContextKey, redactor)
+}
+
+// RedactorFromContext returns the request's redactor, or a strict one outside of a request.
+func RedactorFromContext(ctx context.Context) *Redactor {
+	if redactor, ok := ctx.Value(redactorContextKey).(*Redactor); ok && redactor != nil {
+		return redactor
+	}
+
+	return CreateRedactor(cfe.RedactionPolicyStrict)
+}
+
+// logDynamoDbCall logs the input and output of a DynamoDB call at Debug, with attribute values
+// other than the safe ones masked by the request's redaction policy.
+func logDynamoDbCall(ctx context.Context, operation string, input interface{}, output interface{}, err error) {
+	logger := slog.Default()
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	redactor := RedactorFromContext(ctx)
+
+	attrs := []any{"Operation", operation, "Input", SanitizeDynamoDbValue(redactor, input)}
+	if err != nil {
+		attrs = append(attrs, "Error", err.Error())
+	} else {
+		attrs = append(attrs, "Output", SanitizeDynamoDbValue(redactor, output))
+	}
+
+	logger.DebugContext(ctx, "DynamoDB Call", attrs...)
+}
+
+// SanitizeDynamoDbValue converts a DynamoDB input or output to a loggable value, masking attribute values.
+func SanitizeDynamoDbValue(redactor *Redactor, value interface{}) interface{} {
+	return sanitizeDynamoDbValue(redactor, reflect.ValueOf(value), "")
+}
+
+func sanitizeDynamoDbValue(redactor *Redactor, value reflect.Value, attributeName string) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+
+	if value.Kind() == reflect.Interface && value.Type() == attributeValueType {
+		if value.IsNil() {
+			return nil
+		}
+
+		plain := attributeValueForLog(value.Interface().(types.AttributeValue))
+		if debugSafeAttributes[attributeName] {
+			return plain
+		}
+
+		return maskLeaves(redactor, plain, attributeName)
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		return sanitizeDynamoDbValue(redactor, value.Elem(), attributeName)
+	case reflect.Struct:
+		result := make(map[string]interface{})
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() || field.Name == "ResultMetadata" || value.Field(i).IsZero() {
+				continue
+			}
+
+			result[field.Name] = sanitizeDynamoDbValue(redactor, value.Field(i), attributeName)
+		}
+
+		return result
+	case reflect.Map:
+		result := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			result[key] = sanitizeDynamoDbValue(redactor, iter.Value(), key)
+		}
+
+		return result
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items[i] = sanitizeDynamoDbValue(redactor, value.Index(i), attributeName)
+		}
+
+		return items
+	default:
+		return value.Interface()
+	}
+}
+
+func attributeValueForLog(attributeValue types.AttributeValue) interface{} {
+	switch typed := attributeValue.(type) {
+	case *types.AttributeValueMemberS:
+		return typed.Value
+	case *types.AttributeValueMemberN:
+		return typed.Value
+	case *types.AttributeValueMemberBOOL:
+		return typed.Value
+	case *types.AttributeValueMemberNULL:
+		return nil
+	case *types.AttributeValueMemberSS:
+		return typed.Value
+	case *types.AttributeValueMemberNS:
+		return typed.Value
+	case *types.AttributeValueMemberM:
+		items := make(map[string]interface{}, len(typed.Value))
+		for key, item := range typed.Value {
+			items[key] = attributeValueForLog(item)
+		}
+		return items
+	case *types.AttributeValueMemberL:
+		items := make([]interface{}, len(typed.Value))
+		for i, item := range typed.Value {
+			items[i] = attributeValueForLog(item)
+		}
+		return items
+	default:
+		return "[binary]"
+	}
+}
+
+func maskLeaves(redactor *Redactor, value interface{}, attributeName string) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return maskAttribute(redactor, typed, attributeName)
+	case []string:
+		items := make([]interface{}, len(typed))
+		for i, item := range typed {
+			items[i] = maskAttribute(redactor, item, attributeName)
+		}
+		return items
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = maskLeaves(redactor, item, key)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = maskLeaves(redactor, item, attributeName)
+		}
+	}
+
+	return value
+}
+
+func maskAttribute(redactor *Redactor, value string, attributeName string) interface{} {
+	if tag, ok := debugAttributeTags[attributeName]; ok {
+		return redactor.applyTag(tag, reflect.ValueOf(value))
+	}
+
+	return redactor.Mask(value)
+}
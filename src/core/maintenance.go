@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MaintenanceMiddleware rejects write roles with a 503 while the service is read-only, set by the
+// READ_ONLY setting or the read-only flag, so reads keep serving during table migrations. Callers
+// with the break-glass cf:bypass:maintenance permission can still write.
+func MaintenanceMiddleware(role cfe.LambdaRole, readOnly bool, retryAfter time.Duration, flags *FeatureFlags) Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			if !role.IsWrite() || !IsReadOnly(ctx, readOnly, flags) {
+				return next(ctx, apiRequest)
+			}
+
+			// Wildcards and cf:superadmin do not bypass, the permission must be granted explicitly
+			if cfe.GetPermissions(apiRequest.RequestContext.Authorizer).Contains(cfe.BypassMaintenance.String()) {
+				slog.Default().Warn("Bypassing read-only mode", "Role", role.String())
+				MetricsFromContext(ctx).IncrementCounter("MaintenanceBypassed")
+
+				return next(ctx, apiRequest)
+			}
+
+			MetricsFromContext(ctx).IncrementCounter("MaintenanceRejected")
+
+			response := ErrorResponse(ctx, cfe.ErrorMaintenance("The service is read-only for maintenance, please retry later."))
+			if retryAfter > 0 {
+				SetResponseHeader(&response, "Retry-After", formatSeconds(retryAfter))
+			}
+
+			return response
+		}
+	}
+}
+
+func IsReadOnly(ctx context.Context, readOnly bool, flags *FeatureFlags) bool {
+	return readOnly || flags.IsEnabled(ctx, FlagReadOnly, false)
+}
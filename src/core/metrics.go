@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cfe "cf-user/core/enums"
+)
+
+// Metrics collects values during a request and writes them to stdout using the
+// CloudWatch Embedded Metric Format, where CloudWatch extracts them asynchronously.
+type Metrics struct {
+	namespace  string
+	dimensions []MetricDimension
+	properties map[string]interface{}
+	sets       map[string]*metricSet
+	writer     io.Writer
+	mutex      sync.Mutex
+}
+
+type MetricDimension struct {
+	Name  string
+	Value string
+}
+
+type metricSet struct {
+	dimensions []MetricDimension
+	names      []string
+	units      map[string]cfe.MetricUnit
+	values     map[string][]float64
+}
+
+func CreateMetrics(namespace string, writer io.Writer, dimensions ...MetricDimension) *Metrics {
+	return &Metrics{
+		namespace:  namespace,
+		dimensions: dimensions,
+		properties: make(map[string]interface{}),
+		sets:       make(map[string]*metricSet),
+		writer:     writer,
+	}
+}
+
+func (metrics *Metrics) AddMetric(name string, unit cfe.MetricUnit, value float64) {
+	metrics.AddDimensionedMetric(name, unit, value)
+}
+
+// AddDimensionedMetric records a value against the default dimensions plus the given ones.
+// Values sharing the same extra dimensions are written together in a single record.
+func (metrics *Metrics) AddDimensionedMetric(name string, unit cfe.MetricUnit, value float64, dimensions ...MetricDimension) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	key := dimensionSetKey(dimensions)
+
+	set, ok := metrics.sets[key]
+	if !ok {
+		set = &metricSet{
+			dimensions: dimensions,
+			units:      make(map[string]cfe.MetricUnit),
+			values:     make(map[string][]float64),
+		}
+		metrics.sets[key] = set
+	}
+
+	if _, exists := set.values[name]; !exists {
+		set.names = append(set.names, name)
+		set.units[name] = unit
+	}
+
+	set.values[name] = append(set.values[name], value)
+}
+
+func (metrics *Metrics) IncrementCounter(name string, dimensions ...MetricDimension) {
+	metrics.AddDimensionedMetric(name, cfe.MetricUnitCount, 1, dimensions...)
+}
+
+func (metrics *Metrics) AddDuration(name string, start time.Time, dimensions ...MetricDimension) {
+	metrics.AddDimensionedMetric(name, cfe.MetricUnitMilliseconds, float64(time.Since(start).Microseconds())/1000, dimensions...)
+}
+
+// SetProperty adds a searchable, non-dimension value to every record written by Flush.
+func (metrics *Metrics) SetProperty(key string, value interface{}) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.properties[key] = value
+}
+
+func (metrics *Metrics) Flush() error {
+	if metrics == nil {
+		return nil
+	}
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	keys := make([]string, 0, len(metrics.sets))
+	for key := range metrics.sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	timestamp := time.Now().UnixMilli()
+
+	for _, key := range keys {
+		record, err := json.Marshal(metrics.createRecord(metrics.sets[key], timestamp))
+		if err != nil {
+			return fmt.Errorf("unable to serialize metric record: %v", err.Error())
+		}
+
+		_, err = fmt.Fprintln(metrics.writer, string(record))
+		if err != nil {
+			return fmt.Errorf("unable to write metric record: %v", err.Error())
+		}
+	}
+
+	metrics.sets = make(map[string]*metricSet)
+
+	return nil
+}
+
+func (metrics *Metrics) createRecord(set *metricSet, timestamp int64) map[string]interface{} {
+	record := make(map[string]interface{})
+
+	for key, value := range metrics.properties {
+		record[key] = value
+	}
+
+	dimensionNames := make([]string, 0, len(metrics.dimensions)+len(set.dimensions))
+	for _, dimension := range append(append([]MetricDimension{}, metrics.dimensions...), set.dimensions...) {
+		dimensionNames = append(dimensionNames, dimension.Name)
+		record[dimension.Name] = dimension.Value
+	}
+
+	metricDefinitions := make([]map[string]string, 0, len(set.names))
+	for _, name := range set.names {
+		metricDefinitions = append(metricDefinitions, map[string]string{
+			"Name": name,
+			"Unit": set.units[name].String(),
+		})
+
+		values := set.values[name]
+		if len(values) == 1 {
+			record[name] = values[0]
+		} else {
+			record[name] = values
+		}
+	}
+
+	record["_aws"] = map[string]interface{}{
+		"Timestamp": timestamp,
+		"CloudWatchMetrics": []map[string]interface{}{
+			{
+				"Namespace":  metrics.namespace,
+				"Dimensions": [][]string{dimensionNames},
+				"Metrics":    metricDefinitions,
+			},
+		},
+	}
+
+	return record
+}
+
+func dimensionSetKey(dimensions []MetricDimension) string {
+	parts := make([]string, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		parts = append(parts, dimension.Name+"="+dimension.Value)
+	}
+
+	return strings.Join(parts, "|")
+}
+
+func ContextWithMetrics(ctx context.Context, metrics *Metrics) context.Context {
+	return context.WithValue(ctx, metricsContextKey, metrics)
+}
+
+// MetricsFromContext returns nil when no metrics are attached, which is safe to record against.
+func MetricsFromContext(ctx context.Context) *Metrics {
+	metrics, _ := ctx.Value(metricsContextKey).(*Metrics)
+	return metrics
+}
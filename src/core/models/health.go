@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// HealthResponse follows the draft "Health Check Response Format for HTTP APIs" served as
+// application/health+json, with the stage and cold start added for the synthetic monitors.
+type HealthResponse struct {
+	Status      string                   `json:"status"`
+	Version     string                   `json:"version"`
+	ReleaseId   string                   `json:"releaseId"`
+	ServiceId   string                   `json:"serviceId"`
+	Description string                   `json:"description,omitempty"`
+	Stage       string                   `json:"stage"`
+	ColdStart   bool                     `json:"coldStart"`
+	Checks      map[string][]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck reports one component, e.g. the user table or one of its indexes, with its latency in milliseconds.
+type HealthCheck struct {
+	ComponentId   string    `json:"componentId"`
+	ComponentType string    `json:"componentType"`
+	ObservedValue float64   `json:"observedValue"`
+	ObservedUnit  string    `json:"observedUnit"`
+	Status        string    `json:"status"`
+	Time          time.Time `json:"time"`
+	Output        string    `json:"output,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+type IdempotencyRecord struct {
+	PK              string
+	SK              string
+	Status          string
+	RequestHash     string
+	StatusCode      int
+	ResponseBody    string
+	ResponseHeaders map[string]string
+	IsBase64Encoded bool
+	LockExpiresAt   int64 // epoch seconds after which an in progress request is considered abandoned
+	ExpiresAt       int64 // epoch seconds, used as the table TTL attribute
+	CreatedDate     time.Time
+	UpdatedDate     time.Time
+}
@@ -0,0 +1,9 @@
+package models
+
+type RateLimitBucket struct {
+	PK        string
+	SK        string
+	Tokens    float64
+	UpdatedAt int64 // epoch milliseconds of the last refill
+	ExpiresAt int64 // epoch seconds, used as the table TTL attribute
+}
@@ -0,0 +1,9 @@
+package models
+
+// UniqueClaim reserves a username or identity subject for one user. Claims are written in the same
+// transaction as the user, so two users can never share one, even when set by concurrent requests.
+type UniqueClaim struct {
+	PK     string
+	SK     string
+	UserId string
+}
@@ -7,23 +7,31 @@ import (
 )
 
 type User struct {
-	PK             *string         `json:"omitempty"`
-	SK             *string         `json:"omitempty"`
-	UserId         string          `json:"userId"`
-	Username       string          `json:"username"`
-	FirstName      *string         `json:"firstName"`
-	LastName       *string         `json:"lastName"`
-	PhoneNumber    *string         `json:"phoneNumber"`
-	EmailAddress   string          `json:"emailAddress"`
-	PrimaryAddress *Address        `json:"primaryAddress"`
-	BillingAddress *Address        `json:"billingAddress"`
-	ProfileImageId *string         `json:"profileImageId"`
-	Biography      *string         `json:"biography"`
-	AccountType    cfe.AccountType `json:"accountType"`
-	GSI1PK         *string         `json:"omitempty"`
-	GSI1SK         *string         `json:"omitempty"`
-	GSI2PK         *string         `json:"omitempty"`
-	GSI2SK         *string         `json:"omitempty"`
-	CreatedDate    time.Time       `json:"createdDate"`
-	UpdatedDate    time.Time       `json:"updatedDate"`
+	PK              *string         `json:"-"`
+	SK              *string         `json:"-"`
+	UserId          string          `json:"userId"`
+	Username        string          `json:"username"`
+	FirstName       *string         `json:"firstName" log:"initial"`
+	LastName        *string         `json:"lastName" log:"initial"`
+	PhoneNumber     *string         `json:"phoneNumber" log:"mask"`
+	EmailAddress    string          `json:"emailAddress" log:"mask"`
+	PrimaryAddress  *Address        `json:"primaryAddress"`
+	BillingAddress  *Address        `json:"billingAddress"`
+	ProfileImageId  *string         `json:"profileImageId"`
+	Biography       *string         `json:"biography"`
+	AccountType     cfe.AccountType `json:"accountType"`
+	IdentitySubject *string         `json:"-"` // identity provider subject, passed by the authorizer as requesterOid
+	GSI1PK          *string         `json:"-"`
+	GSI1SK          *string         `json:"-"`
+	GSI2PK          *string         `json:"-"`
+	GSI2SK          *string         `json:"-"`
+	GSI3PK          *string         `json:"-"`
+	GSI3SK          *string         `json:"-"`
+	CreatedDate     time.Time       `json:"createdDate"`
+	UpdatedDate     time.Time       `json:"updatedDate"`
+}
+
+// IsOwnedBy reports whether the user is linked to the given identity provider subject.
+func (user *User) IsOwnedBy(requesterOid string) bool {
+	return len(requesterOid) > 0 && user.IdentitySubject != nil && *user.IdentitySubject == requesterOid
 }
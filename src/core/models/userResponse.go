@@ -0,0 +1,153 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/go-playground/validator"
+)
+
+// userFieldAttributes is the allowlist of fields callers may select with ?fields=, mapped to the
+// User attributes each one is read from.
+var userFieldAttributes = map[string]string{
+	"userId":         "UserId",
+	"username":       "Username",
+	"firstName":      "FirstName",
+	"lastName":       "LastName",
+	"phoneNumber":    "PhoneNumber",
+	"emailAddress":   "EmailAddress",
+	"primaryAddress": "PrimaryAddress",
+	"billingAddress": "BillingAddress",
+	"profileImageId": "ProfileImageId",
+	"biography":      "Biography",
+	"accountType":    "AccountType",
+	"createdDate":    "CreatedDate",
+	"updatedDate":    "UpdatedDate",
+}
+
+// Attributes read for every selection, as handlers need them for ownership and Last-Modified
+var requiredUserAttributes = []string{"UserId", "IdentitySubject", "UpdatedDate"}
+
+// UserResponse is the API representation of a User. Fields outside of the requested view are
+// left nil and omitted, so storage keys and private details are never serialized to callers.
+type UserResponse struct {
+	UserId         string           `json:"userId"`
+	Username       string           `json:"username,omitempty"`
+	FirstName      *string          `json:"firstName,omitempty" log:"initial"`
+	LastName       *string          `json:"lastName,omitempty" log:"initial"`
+	PhoneNumber    *string          `json:"phoneNumber,omitempty" log:"mask"`
+	EmailAddress   *string          `json:"emailAddress,omitempty" log:"mask"`
+	PrimaryAddress *Address         `json:"primaryAddress,omitempty"`
+	BillingAddress *Address         `json:"billingAddress,omitempty"`
+	ProfileImageId *string          `json:"profileImageId,omitempty"`
+	Biography      *string          `json:"biography,omitempty"`
+	AccountType    *cfe.AccountType `json:"accountType,omitempty"`
+	CreatedDate    *time.Time       `json:"createdDate,omitempty"`
+	UpdatedDate    *time.Time       `json:"updatedDate,omitempty"`
+	View           cfe.UserView     `json:"-"`
+	lastModified   time.Time
+}
+
+// LastModified is known for every view, even when the updatedDate field is not exposed.
+func (response *UserResponse) LastModified() time.Time {
+	return response.lastModified
+}
+
+func CreateUserResponse(user *User, view cfe.UserView) *UserResponse {
+	if user == nil {
+		return nil
+	}
+
+	response := &UserResponse{
+		UserId:         user.UserId,
+		Username:       user.Username,
+		FirstName:      user.FirstName,
+		ProfileImageId: user.ProfileImageId,
+		Biography:      user.Biography,
+		View:           view,
+		lastModified:   user.UpdatedDate,
+	}
+
+	if view == cfe.UserViewFull {
+		accountType := user.AccountType
+		createdDate := user.CreatedDate
+		updatedDate := user.UpdatedDate
+		emailAddress := user.EmailAddress
+
+		response.LastName = user.LastName
+		response.PhoneNumber = user.PhoneNumber
+		response.EmailAddress = &emailAddress
+		response.PrimaryAddress = user.PrimaryAddress
+		response.BillingAddress = user.BillingAddress
+		response.AccountType = &accountType
+		response.CreatedDate = &createdDate
+		response.UpdatedDate = &updatedDate
+	}
+
+	return response
+}
+
+// SelectFields clears every field not in the given selection, keeping userId. An empty selection keeps all fields.
+func (response *UserResponse) SelectFields(fields []string) {
+	if response == nil || len(fields) == 0 {
+		return
+	}
+
+	selected := make(map[string]bool)
+	for _, field := range fields {
+		selected[field] = true
+	}
+
+	value := reflect.ValueOf(response).Elem()
+	for name, index := range userResponseFieldIndexes {
+		if name != "userId" && !selected[name] {
+			value.Field(index).SetZero()
+		}
+	}
+}
+
+// GetUserAttributes returns the User attributes to project when reading the given fields,
+// or nil to read the whole item.
+func GetUserAttributes(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	attributes := append([]string{}, requiredUserAttributes...)
+	for _, field := range fields {
+		if attribute, ok := userFieldAttributes[field]; ok {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	return attributes
+}
+
+func GetUserFieldValidator() (string, func(fl validator.FieldLevel) bool) {
+	return "is_user_field", func(fl validator.FieldLevel) bool {
+		_, ok := userFieldAttributes[fl.Field().String()]
+		return ok
+	}
+}
+
+var userResponseFieldIndexes = func() map[string]int {
+	indexes := make(map[string]int)
+
+	responseType := reflect.TypeOf(UserResponse{})
+	for i := 0; i < responseType.NumField(); i++ {
+		field := responseType.Field(i)
+		if _, ok := userFieldAttributes[jsonName(field)]; ok {
+			indexes[jsonName(field)] = i
+		}
+	}
+
+	return indexes
+}()
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
@@ -0,0 +1,288 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	ContentTypeHeader     = "Content-Type"
+	ContentEncodingHeader = "Content-Encoding"
+	AcceptHeader          = "Accept"
+	AcceptEncodingHeader  = "Accept-Encoding"
+	VaryHeader            = "Vary"
+)
+
+const (
+	MediaTypeJson    = "application/json"
+	MediaTypeCbor    = "application/cbor"
+	MediaTypeMsgpack = "application/msgpack"
+)
+
+// Bodies smaller than this are sent uncompressed, as compression would barely reduce them.
+const DefaultCompressionMinBytes = 1024
+
+// Supported media types in order of preference when the Accept header weighs them equally.
+var mediaTypes = []string{MediaTypeJson, MediaTypeCbor, MediaTypeMsgpack}
+
+var mediaTypeAliases = map[string]string{
+	"application/x-msgpack":   MediaTypeMsgpack,
+	"application/vnd.msgpack": MediaTypeMsgpack,
+}
+
+// Supported content codings in order of preference when the Accept-Encoding header weighs them equally.
+var contentCodings = []string{"br", "gzip"}
+
+// ContentNegotiationMiddleware re-encodes JSON responses as CBOR or MessagePack when the Accept
+// header prefers them, and compresses bodies of at least minBytes with br or gzip when the
+// Accept-Encoding header allows it. Binary bodies are base64 encoded for API Gateway, which
+// decodes them as the media types are registered as binary on the API.
+func ContentNegotiationMiddleware(minBytes int) Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			response := next(ctx, apiRequest)
+
+			AddVaryHeader(&response, AcceptHeader)
+			AddVaryHeader(&response, AcceptEncodingHeader)
+
+			if len(response.Body) == 0 || response.IsBase64Encoded {
+				return response
+			}
+			if _, ok := response.Headers[ContentTypeHeader]; !ok {
+				SetResponseHeader(&response, ContentTypeHeader, MediaTypeJson)
+			}
+
+			body := []byte(response.Body)
+			binary := false
+
+			accept, _ := GetHeader(apiRequest.Headers, AcceptHeader)
+			mediaType := NegotiateMediaType(accept)
+			if mediaType != MediaTypeJson && response.Headers[ContentTypeHeader] == MediaTypeJson {
+				encoded, err := EncodeJsonAs(body, mediaType)
+				if err != nil {
+					MetricsFromContext(ctx).IncrementCounter("EncodingErrors")
+				} else {
+					body = encoded
+					binary = true
+					SetResponseHeader(&response, ContentTypeHeader, mediaType)
+					weakenETag(&response)
+				}
+			}
+
+			acceptEncoding, _ := GetHeader(apiRequest.Headers, AcceptEncodingHeader)
+			coding := NegotiateContentCoding(acceptEncoding)
+			if len(coding) > 0 && len(body) >= minBytes {
+				compressed, err := Compress(body, coding)
+				if err != nil {
+					MetricsFromContext(ctx).IncrementCounter("EncodingErrors")
+				} else {
+					body = compressed
+					binary = true
+					SetResponseHeader(&response, ContentEncodingHeader, coding)
+					weakenETag(&response)
+				}
+			}
+
+			if binary {
+				response.Body = base64.StdEncoding.EncodeToString(body)
+				response.IsBase64Encoded = true
+			}
+
+			return response
+		}
+	}
+}
+
+// weakenETag marks the entity tag weak once the body is re-encoded or compressed. It was computed
+// from the JSON body, so it only holds for other representations weakly, while still matching
+// If-None-Match under weak comparison.
+func weakenETag(response *events.APIGatewayProxyResponse) {
+	if etag, ok := response.Headers[ETagHeader]; ok && !strings.HasPrefix(etag, "W/") {
+		SetResponseHeader(response, ETagHeader, "W/"+etag)
+	}
+}
+
+// NegotiateMediaType picks the supported media type the Accept header weighs highest, falling
+// back to JSON when nothing supported is acceptable.
+func NegotiateMediaType(accept string) string {
+	best := MediaTypeJson
+	bestWeight := 0.0
+	bestRank := len(mediaTypes)
+
+	for _, entry := range parseWeightedList(accept) {
+		name := entry.name
+		if alias, ok := mediaTypeAliases[name]; ok {
+			name = alias
+		}
+
+		for rank, mediaType := range mediaTypes {
+			if !mediaTypeMatches(name, mediaType) || entry.weight <= 0 {
+				continue
+			}
+
+			if entry.weight > bestWeight || (entry.weight == bestWeight && rank < bestRank) {
+				best, bestWeight, bestRank = mediaType, entry.weight, rank
+			}
+		}
+	}
+
+	return best
+}
+
+// NegotiateContentCoding picks the supported content coding the Accept-Encoding header weighs
+// highest, or an empty string to leave the body uncompressed.
+func NegotiateContentCoding(acceptEncoding string) string {
+	weights := make(map[string]float64)
+	for _, entry := range parseWeightedList(acceptEncoding) {
+		weights[entry.name] = entry.weight
+	}
+
+	best := ""
+	bestWeight := 0.0
+
+	for _, coding := range contentCodings {
+		// A wildcard only covers codings that are not listed, so "br;q=0, *" still refuses br
+		weight, ok := weights[coding]
+		if !ok {
+			weight = weights["*"]
+		}
+
+		if weight > bestWeight {
+			best, bestWeight = coding, weight
+		}
+	}
+
+	return best
+}
+
+// EncodeJsonAs converts a JSON document to the given media type, so every format carries the
+// same field names and values as the JSON representation.
+func EncodeJsonAs(body []byte, mediaType string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse json body: %v", err.Error())
+	}
+	document = normalizeJsonNumbers(document)
+
+	switch mediaType {
+	case MediaTypeCbor:
+		return cbor.Marshal(document)
+	case MediaTypeMsgpack:
+		return msgpack.Marshal(document)
+	case MediaTypeJson:
+		return body, nil
+	default:
+		return nil, fmt.Errorf("unsupported media type: %v", mediaType)
+	}
+}
+
+func Compress(body []byte, coding string) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	var writer interface {
+		Write(p []byte) (int, error)
+		Close() error
+	}
+
+	switch coding {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "br":
+		writer = brotli.NewWriterLevel(&buffer, brotli.DefaultCompression)
+	default:
+		return nil, fmt.Errorf("unsupported content coding: %v", coding)
+	}
+
+	_, err := writer.Write(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress body: %v", err.Error())
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress body: %v", err.Error())
+	}
+
+	return buffer.Bytes(), nil
+}
+
+type weightedValue struct {
+	name   string
+	weight float64
+}
+
+// parseWeightedList parses headers such as "gzip;q=0.8, br" into lowercased names and q weights.
+func parseWeightedList(header string) []weightedValue {
+	values := make([]weightedValue, 0)
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(name) == 0 {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err == nil {
+					weight = parsed
+				}
+			}
+		}
+
+		values = append(values, weightedValue{name: name, weight: weight})
+	}
+
+	return values
+}
+
+func mediaTypeMatches(pattern string, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// normalizeJsonNumbers turns json.Number values into integers where possible, so binary
+// encodings do not widen every number to a float.
+func normalizeJsonNumbers(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case json.Number:
+		if integer, err := typed.Int64(); err == nil {
+			return integer
+		}
+		float, _ := typed.Float64()
+		return float
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = normalizeJsonNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = normalizeJsonNumbers(item)
+		}
+	}
+
+	return value
+}
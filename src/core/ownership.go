@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// OwnershipCheck reports whether the caller owns the resource addressed by the request,
+// which grants access to handlers that allow owners as an alternative to the required role.
+type OwnershipCheck func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (bool, error)
+
+// GetRequesterOid returns the identity provider subject the authorizer resolved for the caller.
+func GetRequesterOid(apiRequest events.APIGatewayProxyRequest) string {
+	requesterOid, _ := apiRequest.RequestContext.Authorizer["requesterOid"].(string)
+	return requesterOid
+}
+
+func ContextWithRequesterOid(ctx context.Context, requesterOid string) context.Context {
+	return context.WithValue(ctx, requesterOidContextKey, requesterOid)
+}
+
+func RequesterOidFromContext(ctx context.Context) string {
+	requesterOid, _ := ctx.Value(requesterOidContextKey).(string)
+	return requesterOid
+}
+
+// UserOwnerCheck matches the user in the userId path parameter against the caller's requesterOid.
+// A user that does not exist is treated as not owned, so the caller is refused rather than told it is missing.
+func UserOwnerCheck(ddbStore *DynamoDbStore) OwnershipCheck {
+	return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (bool, error) {
+		requesterOid := GetRequesterOid(apiRequest)
+		userId, ok := apiRequest.PathParameters["userId"]
+		if len(requesterOid) == 0 || !ok {
+			return false, nil
+		}
+
+		user, err := ddbStore.GetUser(ctx, userId)
+		if err != nil {
+			var respError cfe.ResponseError
+			if errors.As(err, &respError) && respError.ErrorCode == cfe.ErrorCodeNotFound.String() {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		return user.IsOwnedBy(requesterOid), nil
+	}
+}
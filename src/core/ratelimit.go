@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// Concurrent requests from the same caller race on the bucket, so a conditional write is retried a few times.
+const maxRateLimitAttempts = 3
+
+// RateLimit is a token bucket holding up to Capacity requests, refilled continuously at RefillPerSecond.
+// A Capacity of zero disables rate limiting.
+type RateLimit struct {
+	Capacity        float64 `json:"capacity"`
+	RefillPerSecond float64 `json:"refillPerSecond"`
+}
+
+type RateLimitDecision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+var defaultRateLimits = map[cfe.LambdaRole]RateLimit{
+	cfe.CreateUser: {Capacity: 10, RefillPerSecond: 1},
+	cfe.UpdateUser: {Capacity: 20, RefillPerSecond: 2},
+	cfe.DeleteUser: {Capacity: 10, RefillPerSecond: 1},
+	cfe.ReadUser:   {Capacity: 100, RefillPerSecond: 20},
+	cfe.ReadHealth: {Capacity: 10, RefillPerSecond: 1},
+}
+
+// GetRateLimit returns the limit for the given role, overridden by the RATE_LIMITS setting when present.
+// RATE_LIMITS is a JSON object keyed by role, e.g. {"cf:read:user":{"capacity":50,"refillPerSecond":5}}.
+func GetRateLimit(role cfe.LambdaRole, overrides string) (*RateLimit, error) {
+	limit := defaultRateLimits[role]
+
+	if len(overrides) > 0 {
+		var limits map[string]RateLimit
+		err := json.Unmarshal([]byte(overrides), &limits)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse rate limits: %v", err.Error())
+		}
+
+		if override, ok := limits[role.String()]; ok {
+			limit = override
+		}
+	}
+
+	if limit.Capacity < 0 || limit.RefillPerSecond < 0 || (limit.Capacity > 0 && limit.RefillPerSecond == 0) {
+		return nil, fmt.Errorf("invalid rate limit for %v: %+v", role.String(), limit)
+	}
+
+	return &limit, nil
+}
+
+// Take refills the bucket for the time elapsed since it was last updated and consumes a single token.
+// A bucket that was never updated starts full. The bucket is updated in place, even when the request is rejected.
+func (limit RateLimit) Take(bucket *cfm.RateLimitBucket, now time.Time) RateLimitDecision {
+	tokens := limit.Capacity
+	if bucket.UpdatedAt > 0 {
+		elapsed := math.Max(0, float64(now.UnixMilli()-bucket.UpdatedAt)/1000)
+		tokens = math.Min(limit.Capacity, bucket.Tokens+elapsed*limit.RefillPerSecond)
+	}
+
+	decision := RateLimitDecision{}
+	if tokens >= 1 {
+		tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsDuration((1 - tokens) / limit.RefillPerSecond)
+	}
+
+	decision.Remaining = int(math.Floor(tokens))
+	decision.Reset = secondsDuration((limit.Capacity - tokens) / limit.RefillPerSecond)
+
+	bucket.Tokens = tokens
+	bucket.UpdatedAt = now.UnixMilli()
+	bucket.ExpiresAt = now.Add(decision.Reset).Add(time.Minute).Unix()
+
+	return decision
+}
+
+// RateLimitMiddleware throttles each caller, identified by requesterOid or the source IP for
+// unauthenticated routes, using a token bucket stored in the table. The limiter fails open when
+// the bucket cannot be read or written, so a table issue never blocks otherwise healthy requests.
+func RateLimitMiddleware(ddbStore *DynamoDbStore, role cfe.LambdaRole, limit RateLimit) Middleware {
+	return func(next RequestHandlerFunc) RequestHandlerFunc {
+		return func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+			if limit.Capacity == 0 {
+				return next(ctx, apiRequest)
+			}
+
+			decision, err := takeRateLimitToken(ctx, ddbStore, limit, rateLimitKey(apiRequest), role)
+			if err != nil {
+				slog.Default().Error("Unable to apply rate limit", "Error", err.Error())
+				return next(ctx, apiRequest)
+			}
+
+			var response events.APIGatewayProxyResponse
+			if decision.Allowed {
+				response = next(ctx, apiRequest)
+			} else {
+				slog.Default().Warn("Rate limit exceeded", "Role", role.String(), "RetryAfter", decision.RetryAfter.Seconds())
+				MetricsFromContext(ctx).IncrementCounter("RateLimited")
+
+				response = ErrorResponse(ctx, cfe.ErrorTooManyRequests("Rate limit exceeded, please retry later."))
+				SetResponseHeader(&response, "Retry-After", formatSeconds(decision.RetryAfter))
+			}
+
+			SetResponseHeader(&response, RateLimitLimitHeader, strconv.Itoa(int(limit.Capacity)))
+			SetResponseHeader(&response, RateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+			SetResponseHeader(&response, RateLimitResetHeader, formatSeconds(decision.Reset))
+
+			return response
+		}
+	}
+}
+
+func takeRateLimitToken(ctx context.Context, ddbStore *DynamoDbStore, limit RateLimit, key string, role cfe.LambdaRole) (*RateLimitDecision, error) {
+	pk := fmt.Sprintf("RATELIMIT#%v", key)
+	sk := fmt.Sprintf("RATELIMIT#%v", role.String())
+
+	for attempt := 0; attempt < maxRateLimitAttempts; attempt++ {
+		bucket, err := ddbStore.GetRateLimitBucket(ctx, pk, sk)
+		if err != nil {
+			return nil, err
+		}
+
+		// Take updates the bucket in place, so the value read is copied for the conditional write
+		var previousUpdatedAt *int64
+		if bucket != nil {
+			updatedAt := bucket.UpdatedAt
+			previousUpdatedAt = &updatedAt
+		} else {
+			bucket = &cfm.RateLimitBucket{PK: pk, SK: sk}
+		}
+
+		decision := limit.Take(bucket, time.Now())
+
+		saved, err := ddbStore.SaveRateLimitBucket(ctx, bucket, previousUpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if *saved {
+			return &decision, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to update rate limit bucket after %v attempts", maxRateLimitAttempts)
+}
+
+func rateLimitKey(apiRequest events.APIGatewayProxyRequest) string {
+	requesterOid := GetRequesterOid(apiRequest)
+	if len(requesterOid) > 0 {
+		return requesterOid
+	}
+
+	return fmt.Sprintf("IP#%v", apiRequest.RequestContext.Identity.SourceIP)
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Rate limit headers are whole seconds, rounded up so clients never retry too early.
+func formatSeconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	cfe "cf-user/core/enums"
+)
+
+// Fields tagged `log:"redact"` are always replaced with a placeholder, while fields
+// tagged `log:"mask"` keep just enough of the value to be recognisable in the logs.
+// Fields tagged `log:"initial"`, such as names, keep only their first character.
+const (
+	logTagName      = "log"
+	logTagRedact    = "redact"
+	logTagMask      = "mask"
+	logTagInitial   = "initial"
+	redactedLogText = "[REDACTED]"
+)
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type Redactor struct {
+	policy cfe.RedactionPolicy
+}
+
+func CreateRedactor(policy cfe.RedactionPolicy) *Redactor {
+	return &Redactor{
+		policy: policy,
+	}
+}
+
+func (redactor *Redactor) Policy() cfe.RedactionPolicy {
+	return redactor.policy
+}
+
+// Sanitize returns a JSON friendly copy of the given value with the `log` struct tags applied.
+func (redactor *Redactor) Sanitize(value interface{}) interface{} {
+	return redactor.sanitizeValue(reflect.ValueOf(value))
+}
+
+// Mask applies the redaction policy to a single value that is not part of a tagged model.
+func (redactor *Redactor) Mask(value string) string {
+	return redactor.applyTag(logTagMask, reflect.ValueOf(value)).(string)
+}
+
+func (redactor *Redactor) sanitizeValue(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		if isMarshaler(value.Type()) {
+			return value.Interface()
+		}
+
+		return redactor.sanitizeValue(value.Elem())
+	case reflect.Struct:
+		if isMarshaler(value.Type()) || isMarshaler(reflect.PointerTo(value.Type())) {
+			return value.Interface()
+		}
+
+		return redactor.sanitizeStruct(value)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+
+		items := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items[i] = redactor.sanitizeValue(value.Index(i))
+		}
+
+		return items
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+
+		items := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			items[fmt.Sprint(iter.Key().Interface())] = redactor.sanitizeValue(iter.Value())
+		}
+
+		return items
+	default:
+		return value.Interface()
+	}
+}
+
+func (redactor *Redactor) sanitizeStruct(value reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{})
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+
+		if field.Anonymous && name == "" && fieldValue.Kind() == reflect.Struct {
+			for key, nested := range redactor.sanitizeStruct(fieldValue) {
+				result[key] = nested
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+
+		if tag, ok := field.Tag.Lookup(logTagName); ok {
+			result[name] = redactor.applyTag(tag, fieldValue)
+		} else {
+			result[name] = redactor.sanitizeValue(fieldValue)
+		}
+	}
+
+	return result
+}
+
+func (redactor *Redactor) applyTag(tag string, value reflect.Value) interface{} {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if redactor.policy == cfe.RedactionPolicyNone {
+		return redactor.sanitizeValue(value)
+	}
+
+	if tag == logTagMask && redactor.policy == cfe.RedactionPolicyStandard && value.Kind() == reflect.String {
+		return maskString(value.String())
+	}
+
+	if tag == logTagInitial && redactor.policy == cfe.RedactionPolicyStandard && value.Kind() == reflect.String {
+		return initialString(value.String())
+	}
+
+	if tag == logTagMask || tag == logTagRedact || tag == logTagInitial {
+		if value.Kind() == reflect.String {
+			return redactedLogText
+		}
+		if value.IsZero() {
+			return nil
+		}
+		return redactedLogText
+	}
+
+	return redactor.sanitizeValue(value)
+}
+
+func maskString(value string) string {
+	if len(value) == 0 {
+		return value
+	}
+
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		local := []rune(value[:at])
+		return string(local[0]) + strings.Repeat("*", len(local)-1) + value[at:]
+	}
+
+	runes := []rune(value)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+// initialString keeps the first character, padding to a fixed length so the length of the value is not logged.
+func initialString(value string) string {
+	runes := []rune(value)
+	if len(runes) == 0 {
+		return value
+	}
+
+	return string(runes[0]) + "***"
+}
+
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false, false
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return parts[0], omitEmpty, false
+}
+
+func isMarshaler(valueType reflect.Type) bool {
+	return valueType.Implements(jsonMarshalerType) || valueType.Implements(textMarshalerType)
+}
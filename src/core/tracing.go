@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "cf-user"
+
+var tracerProvider *sdktrace.TracerProvider
+var tracingOnce sync.Once
+
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOtlp   = "otlp"
+)
+
+// CreateSpanExporter returns nil for the "none" exporter, in which case spans are never recorded.
+func CreateSpanExporter(ctx context.Context, exporterName string, endpoint string, writer io.Writer) (sdktrace.SpanExporter, error) {
+	switch exporterName {
+	case "", TraceExporterNone:
+		return nil, nil
+	case TraceExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(writer))
+	case TraceExporterOtlp:
+		if len(endpoint) == 0 {
+			return otlptracehttp.New(ctx)
+		}
+
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	default:
+		return nil, fmt.Errorf("no matching trace exporter found for: %v", exporterName)
+	}
+}
+
+// CreateTracerProvider exports spans synchronously, as the Lambda environment is frozen between invocations.
+func CreateTracerProvider(exporter sdktrace.SpanExporter, service string, stage string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(service),
+			semconv.DeploymentEnvironment(stage),
+		)),
+	)
+}
+
+// ContextWithTraceParent continues the X-Ray trace started by API Gateway and the Lambda runtime.
+func ContextWithTraceParent(ctx context.Context, apiRequest events.APIGatewayProxyRequest) context.Context {
+	traceHeader := os.Getenv("_X_AMZN_TRACE_ID")
+	if len(traceHeader) == 0 {
+		traceHeader, _ = GetHeader(apiRequest.Headers, "X-Amzn-Trace-Id")
+	}
+	if len(traceHeader) == 0 {
+		return ctx
+	}
+
+	return xray.Propagator{}.Extract(ctx, propagation.MapCarrier{"X-Amzn-Trace-Id": traceHeader})
+}
+
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+func EndSpan(span trace.Span, err error) {
+	recordSpanError(span, err)
+	span.End()
+}
+
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// InitTracing registers the process wide tracer provider using the OTEL_TRACES_EXPORTER setting.
+// Tracing stays a no-op when no exporter is configured.
+func InitTracing(service string, stage string, exporterName string, endpoint string) error {
+	var err error
+
+	tracingOnce.Do(func() {
+		var exporter sdktrace.SpanExporter
+
+		exporter, err = CreateSpanExporter(context.TODO(), exporterName, endpoint, os.Stdout)
+		if err != nil || exporter == nil {
+			return
+		}
+
+		tracerProvider = CreateTracerProvider(exporter, service, stage)
+		otel.SetTracerProvider(tracerProvider)
+	})
+
+	return err
+}
+
+func FlushTracing(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+
+	return tracerProvider.ForceFlush(ctx)
+}
+
+func startHandlerSpan(ctx context.Context, apiRequest events.APIGatewayProxyRequest, coldstart bool) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, fmt.Sprintf("%v %v", apiRequest.HTTPMethod, apiRequest.Resource),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(apiRequest.HTTPMethod),
+			semconv.HTTPRoute(apiRequest.Resource),
+			semconv.FaaSInvocationID(apiRequest.RequestContext.RequestID),
+			semconv.FaaSColdstart(coldstart),
+		),
+	)
+}
+
+func endHandlerSpan(span trace.Span, response events.APIGatewayProxyResponse) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+	if response.StatusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
+
+	span.End()
+}
+
+func (DynamoDbStore *DynamoDbStore) startSpan(ctx context.Context, method string, operation string, indexName *string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		semconv.DBSystemDynamoDB,
+		semconv.DBOperationName(operation),
+		semconv.AWSDynamoDBTableNames(DynamoDbStore.tableName),
+	}
+	if indexName != nil {
+		attributes = append(attributes, semconv.AWSDynamoDBIndexName(*indexName))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, fmt.Sprintf("DynamoDbStore.%v", method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// recordConsumedCapacity adds the capacity of a store call to its span and the request's capacity tracker.
+func recordConsumedCapacity(ctx context.Context, method string, consumedCapacity *types.ConsumedCapacity) {
+	if consumedCapacity == nil {
+		return
+	}
+
+	CapacityTrackerFromContext(ctx).Add(method, consumedCapacity)
+
+	capacity, err := json.Marshal(consumedCapacity)
+	if err != nil {
+		return
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(semconv.AWSDynamoDBConsumedCapacity(string(capacity)))
+}
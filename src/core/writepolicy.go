@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	cfe "cf-user/core/enums"
+)
+
+// Request struct fields tagged `write:"<permission>"` may only be set by callers holding that
+// permission, e.g. `write:"cf:admin:user"`. Untagged fields can be set by any caller the handler
+// authorizes, including owners. A field counts as set when the request carries a non-zero value.
+const writeTag = "write"
+
+// UnchangedFieldsCheck clears request fields that hold the stored value before write permissions
+// are checked, so callers can send back a resource as they read it without the permission to change it.
+type UnchangedFieldsCheck[TRequest interface{}] func(ctx context.Context, request *TRequest) error
+
+type FieldPermissionError struct {
+	Field      string
+	Permission string
+}
+
+func (err *FieldPermissionError) Error() string {
+	return fmt.Sprintf("field %v requires the %v permission", err.Field, err.Permission)
+}
+
+// HasWritePolicy reports whether the given type declares a write permission on any field.
+func HasWritePolicy(valueType reflect.Type) bool {
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < valueType.NumField(); i++ {
+		if _, ok := valueType.Field(i).Tag.Lookup(writeTag); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckWritePermissions returns an error for every set field whose write permission is not granted.
+func CheckWritePermissions(request interface{}, permissions cfe.Permissions) []*FieldPermissionError {
+	value := reflect.ValueOf(request)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errors []*FieldPermissionError
+
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+
+		permission, ok := field.Tag.Lookup(writeTag)
+		if !ok || len(permission) == 0 || value.Field(i).IsZero() {
+			continue
+		}
+
+		if permissions.Grants(permission) {
+			continue
+		}
+
+		name := field.Name
+		if tagName, _, skip := jsonFieldName(field); !skip && len(tagName) > 0 {
+			name = tagName
+		}
+
+		errors = append(errors, &FieldPermissionError{
+			Field:      name,
+			Permission: permission,
+		})
+	}
+
+	return errors
+}
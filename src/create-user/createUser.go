@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 
@@ -14,15 +15,17 @@ import (
 
 type CreateUserRequest struct {
 	Username       *string      `json:"username" validate:"omitempty,max=300"`
-	FirstName      *string      `json:"firstName" validate:"omitempty,max=300"`
-	LastName       *string      `json:"lastName" validate:"omitempty,max=300"`
-	PhoneNumber    *string      `json:"phoneNumber" validate:"omitempty,max=11"` // 10 digit number or 11 digit including country code
-	EmailAddress   string       `json:"emailAddress" validate:"required,email"`
+	FirstName      *string      `json:"firstName" validate:"omitempty,max=300" log:"initial"`
+	LastName       *string      `json:"lastName" validate:"omitempty,max=300" log:"initial"`
+	PhoneNumber    *string      `json:"phoneNumber" validate:"omitempty,max=11" log:"mask"` // 10 digit number or 11 digit including country code
+	EmailAddress   string       `json:"emailAddress" validate:"required,email" log:"mask"`
 	PrimaryAddress *cfm.Address `json:"primaryAddress" validate:"omitempty"`
 	BillingAddress *cfm.Address `json:"billingAddress" validate:"omitempty"`
 	ProfileImageId *string      `json:"profileImageId" validate:"omitempty,max=26"` // 26 char ULID
 	Biography      *string      `json:"biography" validate:"omitempty,max=4000"`
 	AccountType    *string      `json:"accountType" validate:"omitempty,is_account_type"`
+	// Links the user to an identity provider subject, allowing them to manage their own profile
+	IdentitySubject *string `json:"identitySubject" validate:"omitempty,max=255" log:"mask"`
 }
 
 type CreateUserResponse struct {
@@ -37,17 +40,30 @@ func InitLambda(ddbStore *cfc.DynamoDbStore) {
 	LambdaConfig = cfc.CreateLambaConfig[CreateUserRequest, CreateUserResponse](roleRequired, ddbStore)
 
 	LambdaConfig.FunctionHandler.Validate.RegisterValidation(cfe.GetAccountTypeValidator())
+
+	LambdaConfig.FunctionHandler.Decoding = cfc.StrictDecodeOptions()
+
+	LambdaConfig.FunctionHandler.Use(cfc.DryRunMiddleware())
+	LambdaConfig.FunctionHandler.Use(cfc.IdempotencyMiddleware(LambdaConfig.DynamoDbStore, 24*time.Hour))
 }
 
 func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return LambdaConfig.FunctionHandler.HandleRequest(apiRequest, func(request CreateUserRequest) (*CreateUserResponse, *cfe.ResponseError) {
-		user, _ := LambdaConfig.DynamoDbStore.GetUserByEmail(request.EmailAddress)
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request CreateUserRequest) (*CreateUserResponse, *cfe.ResponseError) {
+		user, _ := LambdaConfig.DynamoDbStore.GetUserByEmail(ctx, request.EmailAddress)
 		if user != nil {
 			e := cfe.ErrorValidation(fmt.Sprintf("User already exists with given email address: %v", request.EmailAddress))
 			return nil, &e
 		}
 
-		LambdaConfig.FunctionHandler.Logger.Info("Creating User", "EmailAddress", request.EmailAddress)
+		if request.IdentitySubject != nil {
+			user, _ = LambdaConfig.DynamoDbStore.GetUserByIdentitySubject(ctx, *request.IdentitySubject)
+			if user != nil {
+				e := cfe.ErrorValidation("User already exists for the given identity subject.")
+				return nil, &e
+			}
+		}
+
+		LambdaConfig.FunctionHandler.Logger.Info("Creating User", "EmailAddress", LambdaConfig.FunctionHandler.Redactor.Mask(request.EmailAddress))
 
 		var username string
 		if request.Username != nil {
@@ -56,6 +72,12 @@ func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (eve
 			username = strings.Split(request.EmailAddress, "@")[0]
 		}
 
+		existingUser, _ := LambdaConfig.DynamoDbStore.GetUserByUsername(ctx, username)
+		if existingUser != nil {
+			e := cfe.ErrorValidation(fmt.Sprintf("User already exists with given username: %v", username))
+			return nil, &e
+		}
+
 		var accountType cfe.AccountType
 		if request.AccountType != nil {
 			aType, err := cfe.GetAccountType(request.AccountType)
@@ -70,25 +92,30 @@ func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (eve
 		}
 
 		createInput := &cfm.User{
-			Username:       username,
-			FirstName:      request.FirstName,
-			LastName:       request.LastName,
-			PhoneNumber:    request.PhoneNumber,
-			EmailAddress:   request.EmailAddress,
-			PrimaryAddress: request.PrimaryAddress,
-			BillingAddress: request.BillingAddress,
-			ProfileImageId: request.ProfileImageId,
-			Biography:      request.Biography,
-			AccountType:    accountType,
+			Username:        username,
+			FirstName:       request.FirstName,
+			LastName:        request.LastName,
+			PhoneNumber:     request.PhoneNumber,
+			EmailAddress:    request.EmailAddress,
+			PrimaryAddress:  request.PrimaryAddress,
+			BillingAddress:  request.BillingAddress,
+			ProfileImageId:  request.ProfileImageId,
+			Biography:       request.Biography,
+			AccountType:     accountType,
+			IdentitySubject: request.IdentitySubject,
 		}
 
-		userId, err := LambdaConfig.DynamoDbStore.CreateUser(createInput)
+		userId, err := LambdaConfig.DynamoDbStore.CreateUser(ctx, createInput)
 
 		if err != nil {
 			parseError := cfe.ErrorGetOrDefault(err)
 			return nil, &parseError
 		}
 
+		if !cfc.DryRunFromContext(ctx) {
+			cfc.MetricsFromContext(ctx).IncrementCounter("UsersCreated", cfc.MetricDimension{Name: "AccountType", Value: accountType.String()})
+		}
+
 		return &CreateUserResponse{
 			UserId: *userId,
 		}, nil
@@ -9,7 +9,9 @@ import (
 	cfe "cf-user/core/enums"
 )
 
-type DeleteUserRequest interface{}
+type DeleteUserRequest struct {
+	UserId string `path:"userId" validate:"required"`
+}
 
 var LambdaConfig *cfc.LambdaConfig[DeleteUserRequest, bool]
 
@@ -17,17 +19,15 @@ func InitLambda(ddbStore *cfc.DynamoDbStore) {
 	roleRequired := cfe.DeleteUser
 
 	LambdaConfig = cfc.CreateLambaConfig[DeleteUserRequest, bool](roleRequired, ddbStore)
+
+	LambdaConfig.FunctionHandler.AllowOwner(cfc.UserOwnerCheck(LambdaConfig.DynamoDbStore))
+
+	LambdaConfig.FunctionHandler.Use(cfc.DryRunMiddleware())
 }
 
 func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return LambdaConfig.FunctionHandler.HandleRequest(apiRequest, func(DeleteUserRequest) (*bool, *cfe.ResponseError) {
-		userId, ok := apiRequest.PathParameters["userId"]
-		if !ok {
-			e := cfe.ErrorValidation("missing path parameter userId")
-			return nil, &e
-		}
-
-		groupDeleted, err := LambdaConfig.DynamoDbStore.DeleteUser(userId)
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request DeleteUserRequest) (*bool, *cfe.ResponseError) {
+		groupDeleted, err := LambdaConfig.DynamoDbStore.DeleteUser(ctx, request.UserId)
 		if err != nil {
 			parseError := cfe.ErrorGetOrDefault(err)
 			return nil, &parseError
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	cfc "cf-user/core"
+	cfconfig "cf-user/core/config"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -50,7 +51,13 @@ func loadConfig() TestUtils {
 
 	os.Setenv("AUTHORIZER_CONFIG_PATH", "/authorizer/config")
 
-	os.Setenv("USER_TABLE_NAME", fmt.Sprintf("%v-%v-app-user", service, stage))
+	settings, err := cfconfig.Load(context.TODO(), cfconfig.EnvSource{}, cfconfig.MapSource{
+		"USER_TABLE_NAME": fmt.Sprintf("%v-%v-app-user", service, stage),
+	})
+	if err != nil {
+		log.Panicf("Failed to load config: %v", err.Error())
+	}
+	cfconfig.Use(settings)
 
 	var cfg aws.Config
 
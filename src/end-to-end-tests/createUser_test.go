@@ -1,6 +1,7 @@
 package endtoendtest
 
 import (
+	"context"
 	"testing"
 
 	"github.com/oklog/ulid/v2"
@@ -10,7 +11,7 @@ import (
 func Test_Create_Identity_Group_Should_Succeed(t *testing.T) {
 	email := "user" + ulid.Make().String() + "@canary-classifind.com"
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	request := GivenCreateUserRequest(&email)
 
@@ -1,6 +1,7 @@
 package endtoendtest
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -10,11 +11,11 @@ import (
 )
 
 func Test_Get_Identity_Group_Should_Succeed(t *testing.T) {
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	startTime := time.Now()
 	args := GivenCreateUserArgs(nil)
-	entityId, err := Fixture.DynamoDbStore.CreateUser(args)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	user, err := WhenWeGetUser(*entityId)
@@ -27,21 +28,21 @@ func Test_Get_Identity_Group_Should_Succeed(t *testing.T) {
 	require.Equal(t, args.FirstName, user.FirstName)
 	require.Equal(t, args.LastName, user.LastName)
 	require.Equal(t, args.PhoneNumber, user.PhoneNumber)
-	require.Equal(t, args.EmailAddress, user.EmailAddress)
+	require.Equal(t, args.EmailAddress, *user.EmailAddress)
 	require.Equal(t, args.PrimaryAddress, user.PrimaryAddress)
 	require.Equal(t, args.BillingAddress, user.BillingAddress)
 	require.Equal(t, args.ProfileImageId, user.ProfileImageId)
 	require.Equal(t, args.Biography, user.Biography)
-	require.Equal(t, accountType, user.AccountType)
-	require.WithinRange(t, user.CreatedDate, startTime, time.Now())
-	require.Equal(t, user.CreatedDate, user.UpdatedDate)
+	require.Equal(t, accountType, *user.AccountType)
+	require.WithinRange(t, *user.CreatedDate, startTime, time.Now())
+	require.Equal(t, *user.CreatedDate, *user.UpdatedDate)
 }
 
 func Test_Get_User_Should_Succeed_By_Email_Or_Username(t *testing.T) {
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	args := GivenCreateUserArgs(nil)
-	_, err := Fixture.DynamoDbStore.CreateUser(args)
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	// Get User by Email
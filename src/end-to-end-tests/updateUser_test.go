@@ -1,16 +1,17 @@
 package endtoendtest
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
 func Test_Update_Identity_Group_Should_Succeed(t *testing.T) {
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	args := GivenCreateUserArgs(nil)
-	entityId, err := Fixture.DynamoDbStore.CreateUser(args)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	request := GivenUpdateUserRequest(nil)
@@ -13,10 +13,10 @@ import (
 	cfuu "cf-user/update-user"
 )
 
-func WhenWeGetUser(groupId string) (*cfm.User, error) {
+func WhenWeGetUser(groupId string) (*cfm.UserResponse, error) {
 	httpRequestUrl := fmt.Sprintf("%v/v1/users/%v", Fixture.ApiGatewayUrl, groupId)
 
-	return sendRequestWithoutBody[cfm.User]("GET", httpRequestUrl)
+	return sendRequestWithoutBody[cfm.UserResponse]("GET", httpRequestUrl)
 }
 
 func WhenWeCreateUser(request cfcu.CreateUserRequest) (*cfcu.CreateUserResponse, error) {
@@ -0,0 +1,51 @@
+package getcurrentuser
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+)
+
+type GetCurrentUserRequest struct {
+	Fields []string `query:"fields" validate:"omitempty,max=20,dive,is_user_field"`
+}
+
+var LambdaConfig *cfc.LambdaConfig[GetCurrentUserRequest, cfm.UserResponse]
+
+func InitLambda(ddbStore *cfc.DynamoDbStore) {
+	roleRequired := cfe.ReadUser
+
+	LambdaConfig = cfc.CreateLambaConfig[GetCurrentUserRequest, cfm.UserResponse](roleRequired, ddbStore)
+
+	// Any caller may read the profile linked to their own identity
+	LambdaConfig.FunctionHandler.AllowWithoutRole()
+
+	LambdaConfig.FunctionHandler.Validate.RegisterValidation(cfm.GetUserFieldValidator())
+
+	LambdaConfig.FunctionHandler.Use(cfc.VaryByCallerMiddleware())
+}
+
+func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request GetCurrentUserRequest) (*cfm.UserResponse, *cfe.ResponseError) {
+		requesterOid := cfc.RequesterOidFromContext(ctx)
+		if len(requesterOid) == 0 {
+			e := cfe.ErrorAuthorization("No identity was provided for the current user.")
+			return nil, &e
+		}
+
+		user, err := LambdaConfig.DynamoDbStore.GetUserByIdentitySubject(ctx, requesterOid, cfm.GetUserAttributes(request.Fields)...)
+		if err != nil {
+			parseError := cfe.ErrorGetOrDefault(err)
+			return nil, &parseError
+		}
+
+		response := cfm.CreateUserResponse(user, cfe.UserViewFull)
+		response.SelectFields(request.Fields)
+
+		return response, nil
+	}), nil
+}
@@ -12,33 +12,55 @@ import (
 	cfm "cf-user/core/models"
 )
 
-type GetUserRequest interface{}
+// Public profiles may be cached by shared caches, while full profiles are only revalidated by the caller
+const publicProfileCacheControl = "public, max-age=60"
+const fullProfileCacheControl = "private, no-cache"
 
-var LambdaConfig *cfc.LambdaConfig[GetUserRequest, cfm.User]
+type GetUserRequest struct {
+	UserId string   `path:"userId" validate:"required,max=320" log:"mask"` // ULID, username or email address
+	Fields []string `query:"fields" validate:"omitempty,max=20,dive,is_user_field"`
+}
+
+var LambdaConfig *cfc.LambdaConfig[GetUserRequest, cfm.UserResponse]
 
 func InitLambda(ddbStore *cfc.DynamoDbStore) {
 	roleRequired := cfe.ReadUser
 
-	LambdaConfig = cfc.CreateLambaConfig[GetUserRequest, cfm.User](roleRequired, ddbStore)
+	LambdaConfig = cfc.CreateLambaConfig[GetUserRequest, cfm.UserResponse](roleRequired, ddbStore)
+
+	// Callers without the role still receive the public profile by ULID, unless they own it
+	LambdaConfig.FunctionHandler.AllowWithoutRole()
+
+	LambdaConfig.FunctionHandler.Validate.RegisterValidation(cfm.GetUserFieldValidator())
+
+	// The view returned depends on the caller, so caches must key responses by their Authorization
+	LambdaConfig.FunctionHandler.Use(cfc.VaryByCallerMiddleware())
+	LambdaConfig.FunctionHandler.Use(cfc.ConditionalGetMiddleware())
+
+	LambdaConfig.FunctionHandler.CacheWith(func(ctx context.Context, response *cfm.UserResponse) string {
+		if response.View == cfe.UserViewPublic {
+			return publicProfileCacheControl
+		}
+
+		return fullProfileCacheControl
+	})
 }
 
 func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return LambdaConfig.FunctionHandler.HandleRequest(apiRequest, func(GetUserRequest) (*cfm.User, *cfe.ResponseError) {
-		userId, ok := apiRequest.PathParameters["userId"]
-		if !ok {
-			e := cfe.ErrorValidation("missing path parameter userId")
-			return nil, &e
-		}
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request GetUserRequest) (*cfm.UserResponse, *cfe.ResponseError) {
+		userId := request.UserId
+		attributes := cfm.GetUserAttributes(request.Fields)
 
 		var user *cfm.User
 
 		_, err := ulid.ParseStrict(userId)
-		if err == nil {
-			user, err = LambdaConfig.DynamoDbStore.GetUser(userId)
+		byUserId := err == nil
+		if byUserId {
+			user, err = LambdaConfig.DynamoDbStore.GetUser(ctx, userId, attributes...)
 		} else if strings.Contains(userId, "@") {
-			user, err = LambdaConfig.DynamoDbStore.GetUserByEmail(userId)
+			user, err = LambdaConfig.DynamoDbStore.GetUserByEmail(ctx, userId, attributes...)
 		} else {
-			user, err = LambdaConfig.DynamoDbStore.GetUserByUsername(userId)
+			user, err = LambdaConfig.DynamoDbStore.GetUserByUsername(ctx, userId, attributes...)
 		}
 
 		if err != nil {
@@ -46,6 +68,23 @@ func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (eve
 			return nil, &parseError
 		}
 
-		return user, nil
+		fullView := user != nil && (cfe.ReadUser.ExistsInAuthContext(apiRequest.RequestContext.Authorizer) || user.IsOwnedBy(cfc.RequesterOidFromContext(ctx)))
+
+		// Lookups by email or username need the role or ownership, so other callers cannot learn
+		// whether an email or username is registered
+		if user == nil || (!byUserId && !fullView) {
+			e := cfe.ErrorNotFound()
+			return nil, &e
+		}
+
+		view := cfe.UserViewPublic
+		if fullView {
+			view = cfe.UserViewFull
+		}
+
+		response := cfm.CreateUserResponse(user, view)
+		response.SelectFields(request.Fields)
+
+		return response, nil
 	}), nil
 }
@@ -0,0 +1,50 @@
+package healthcheck
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	cfc "cf-user/core"
+	cfconfig "cf-user/core/config"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+)
+
+type HealthCheckRequest struct{}
+
+var LambdaConfig *cfc.LambdaConfig[HealthCheckRequest, cfm.HealthResponse]
+
+func InitLambda(ddbStore *cfc.DynamoDbStore) {
+	roleRequired := cfe.ReadHealth
+
+	LambdaConfig = cfc.CreateLambaConfig[HealthCheckRequest, cfm.HealthResponse](roleRequired, ddbStore)
+
+	// Health checks are unauthenticated, callers are only rate limited by source IP
+	LambdaConfig.FunctionHandler.AllowWithoutRole()
+
+	LambdaConfig.FunctionHandler.CacheWith(func(ctx context.Context, response *cfm.HealthResponse) string {
+		return "no-store"
+	})
+	LambdaConfig.FunctionHandler.Use(cfc.HealthMiddleware())
+}
+
+func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Read before handling, as the cold start flag is cleared once the request completes
+	coldStart := LambdaConfig.FunctionHandler.ColdStart()
+	deep := strings.HasSuffix(apiRequest.Resource, "/deep")
+
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request HealthCheckRequest) (*cfm.HealthResponse, *cfe.ResponseError) {
+		settings := cfconfig.Current()
+		response := cfc.CreateHealthResponse(settings.Service, settings.Stage, coldStart)
+
+		if deep {
+			checks, status := cfc.CheckUserTable(ctx, LambdaConfig.DynamoDbStore)
+			response.Checks = checks
+			response.Status = status.String()
+		}
+
+		return response, nil
+	}), nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+
+	logic "cf-user/health-check"
+)
+
+func main() {
+	if logic.LambdaConfig == nil {
+		logic.InitLambda(nil)
+	}
+
+	lambda.Start(logic.Handler)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/joho/godotenv"
 
 	cfc "cf-user/core"
+	cfconfig "cf-user/core/config"
 )
 
 type TestUtils struct {
@@ -36,7 +37,13 @@ func loadConfig() TestUtils {
 	stage := os.Getenv("STAGE")
 	region := os.Getenv("CDK_DEFAULT_REGION")
 
-	os.Setenv("USER_TABLE_NAME", fmt.Sprintf("%v-%v-app-user", service, stage))
+	settings, err := cfconfig.Load(context.TODO(), cfconfig.EnvSource{}, cfconfig.MapSource{
+		"USER_TABLE_NAME": fmt.Sprintf("%v-%v-app-user", service, stage),
+	})
+	if err != nil {
+		log.Panicf("Failed to load config: %v", err.Error())
+	}
+	cfconfig.Use(settings)
 
 	var cfg aws.Config
 
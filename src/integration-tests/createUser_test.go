@@ -1,6 +1,7 @@
 package integrationtest
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -34,7 +35,7 @@ func Test_Create_User_Should_Succeed(t *testing.T) {
 	startTime := time.Now()
 	role := cfe.CreateUser.String()
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	request := GivenCreateUserRequest(nil)
 
@@ -44,7 +45,7 @@ func Test_Create_User_Should_Succeed(t *testing.T) {
 
 	var response = GetDataFromResponse[cfcu.CreateUserResponse](apiResponse)
 
-	user, err := Fixture.DynamoDbStore.GetUser(response.UserId)
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), response.UserId)
 	require.Nil(t, err)
 
 	accountType := user.AccountType.String()
@@ -74,7 +75,7 @@ func Test_Create_User_Should_Succeed_With_Minimal_Request(t *testing.T) {
 	startTime := time.Now()
 	role := cfe.CreateUser.String()
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	request := GivenCreateMinimalUserRequest(nil)
 
@@ -84,7 +85,7 @@ func Test_Create_User_Should_Succeed_With_Minimal_Request(t *testing.T) {
 
 	var response = GetDataFromResponse[cfcu.CreateUserResponse](apiResponse)
 
-	user, err := Fixture.DynamoDbStore.GetUser(response.UserId)
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), response.UserId)
 	require.Nil(t, err)
 
 	accountType := user.AccountType.String()
@@ -109,3 +110,115 @@ func Test_Create_User_Should_Succeed_With_Minimal_Request(t *testing.T) {
 	require.WithinRange(t, user.CreatedDate, startTime, time.Now())
 	require.Equal(t, user.CreatedDate, user.UpdatedDate)
 }
+
+func Test_Create_User_Should_Replay_Response_For_Same_Idempotency_Key(t *testing.T) {
+	role := cfe.CreateUser.String()
+	requesterId := ulid.Make().String()
+	idempotencyKey := ulid.Make().String()
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	request := GivenCreateUserRequest(nil)
+
+	firstResponse, err := WhenWeCreateUserIdempotently(request, idempotencyKey, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, firstResponse.StatusCode)
+
+	retryResponse, err := WhenWeCreateUserIdempotently(request, idempotencyKey, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, retryResponse.StatusCode)
+	require.Equal(t, "true", retryResponse.Headers["Idempotent-Replayed"])
+	require.Equal(t, firstResponse.Body, retryResponse.Body)
+}
+
+func Test_Create_User_Should_Not_Replay_Errors_For_Same_Idempotency_Key(t *testing.T) {
+	role := cfe.CreateUser.String()
+	missingRole := "cf:fake:role"
+	requesterId := ulid.Make().String()
+	idempotencyKey := ulid.Make().String()
+
+	request := GivenCreateUserRequest(nil)
+
+	apiResponse, err := WhenWeCreateUserIdempotently(request, idempotencyKey, &missingRole, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 403, apiResponse.StatusCode)
+
+	// Once granted the role, the retry runs again rather than replaying the 403
+	apiResponse, err = WhenWeCreateUserIdempotently(request, idempotencyKey, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+	require.NotContains(t, apiResponse.Headers, "Idempotent-Replayed")
+}
+
+func Test_Create_User_Should_Reject_Reused_Idempotency_Key_With_Different_Body(t *testing.T) {
+	role := cfe.CreateUser.String()
+	requesterId := ulid.Make().String()
+	idempotencyKey := ulid.Make().String()
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	apiResponse, err := WhenWeCreateUserIdempotently(GivenCreateUserRequest(nil), idempotencyKey, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	apiResponse, err = WhenWeCreateUserIdempotently(GivenCreateUserRequest(nil), idempotencyKey, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 409, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, "IDEMPOTENCY_KEY_REUSED")
+}
+
+func Test_Create_User_Should_Not_Persist_Dry_Run(t *testing.T) {
+	role := cfe.CreateUser.String()
+
+	request := GivenCreateUserRequest(nil)
+
+	apiResponse, err := WhenWeCreateUserAsDryRun(request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+	require.Equal(t, "dry-run", apiResponse.Headers["Preference-Applied"])
+
+	var response = GetDataFromResponse[cfcu.CreateUserResponse](apiResponse)
+	require.NotEmpty(t, response.UserId)
+
+	user, _ := Fixture.DynamoDbStore.GetUser(context.TODO(), response.UserId)
+	require.Nil(t, user)
+
+	user, _ = Fixture.DynamoDbStore.GetUserByEmail(context.TODO(), request.EmailAddress)
+	require.Nil(t, user)
+}
+
+func Test_Create_User_Should_Report_Conflicts_In_Dry_Run(t *testing.T) {
+	role := cfe.CreateUser.String()
+	email := "user" + ulid.Make().String() + "@canary-classifind.com"
+
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), GivenCreateUserArgs(&email))
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeCreateUserAsDryRun(GivenCreateUserRequest(&email), &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 400, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, "User already exists with given email address")
+}
+
+func Test_Create_User_Should_Report_Username_Conflicts_In_Dry_Run(t *testing.T) {
+	role := cfe.CreateUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	request := GivenCreateUserRequest(nil)
+	request.Username = &args.Username
+
+	apiResponse, err := WhenWeCreateUserAsDryRun(request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 400, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, "User already exists with given username")
+
+	apiResponse, err = WhenWeCreateUser(request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 400, apiResponse.StatusCode)
+
+	user, _ := Fixture.DynamoDbStore.GetUserByEmail(context.TODO(), request.EmailAddress)
+	require.Nil(t, user)
+}
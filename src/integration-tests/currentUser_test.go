@@ -0,0 +1,109 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+
+	cfm "cf-user/core/models"
+	cfgcu "cf-user/get-current-user"
+	cfucu "cf-user/update-current-user"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	cfgcu.InitLambda(Fixture.DynamoDbStore)
+	cfucu.InitLambda(Fixture.DynamoDbStore)
+}
+
+func GivenLinkedUser(t *testing.T) (*cfm.User, string) {
+	requesterId := ulid.Make().String()
+
+	args := GivenCreateUserArgs(nil)
+	args.IdentitySubject = &requesterId
+
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	return args, requesterId
+}
+
+func Test_Get_Current_User_Should_Succeed(t *testing.T) {
+	role := ""
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	args, requesterId := GivenLinkedUser(t)
+
+	apiResponse, err := WhenWeGetCurrentUser(&role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	user := GetDataFromResponse[cfm.UserResponse](apiResponse)
+
+	require.Equal(t, args.UserId, user.UserId)
+	require.Equal(t, args.EmailAddress, *user.EmailAddress)
+}
+
+func Test_Get_Current_User_Should_Fail_When_Not_Linked(t *testing.T) {
+	role := ""
+	requesterId := ulid.Make().String()
+
+	apiResponse, err := WhenWeGetCurrentUser(&role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 404, apiResponse.StatusCode)
+}
+
+func Test_Update_Current_User_Should_Succeed(t *testing.T) {
+	role := ""
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	args, requesterId := GivenLinkedUser(t)
+	request := GivenUpdateUserRequest(nil)
+
+	apiResponse, err := WhenWeUpdateCurrentUser(request, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), args.UserId)
+	require.Nil(t, err)
+	require.Equal(t, request.FirstName, user.FirstName)
+	require.Equal(t, request.Biography, user.Biography)
+}
+
+func Test_Update_User_Should_Allow_Owner_Without_Role(t *testing.T) {
+	role := ""
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	args, requesterId := GivenLinkedUser(t)
+	request := GivenUpdateUserRequest(nil)
+
+	apiResponse, err := WhenWeUpdateUser(args.UserId, request, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+
+	otherRequesterId := ulid.Make().String()
+
+	apiResponse, err = WhenWeUpdateUser(args.UserId, request, &role, &otherRequesterId)
+	require.Nil(t, err)
+	require.Equal(t, 403, apiResponse.StatusCode)
+}
+
+func Test_Get_User_Should_Return_Full_Profile_To_Owner(t *testing.T) {
+	role := ""
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	args, requesterId := GivenLinkedUser(t)
+
+	apiResponse, err := WhenWeGetUser(args.UserId, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	user := GetDataFromResponse[cfm.UserResponse](apiResponse)
+
+	require.Equal(t, args.EmailAddress, *user.EmailAddress)
+}
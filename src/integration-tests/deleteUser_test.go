@@ -1,6 +1,7 @@
 package integrationtest
 
 import (
+	"context"
 	"testing"
 
 	cfe "cf-user/core/enums"
@@ -28,13 +29,33 @@ func Test_Delete_Identity_Group_Should_Fail_When_Role_Missing(t *testing.T) {
 func Test_Delete_Identity_Group_Should_Succeed(t *testing.T) {
 	role := cfe.DeleteUser.String()
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	args := GivenCreateUserArgs(nil)
-	entityId, err := Fixture.DynamoDbStore.CreateUser(args)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	apiResponse, err := WhenWeDeleteUser(*entityId, &role, nil)
 	require.Nil(t, err)
 	require.Equal(t, 204, apiResponse.StatusCode)
 }
+
+func Test_Delete_User_Should_Not_Persist_Dry_Run(t *testing.T) {
+	role := cfe.DeleteUser.String()
+
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), GivenCreateUserArgs(nil))
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeDeleteUserAsDryRun(*entityId, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+	require.Equal(t, "dry-run", apiResponse.Headers["Preference-Applied"])
+
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), *entityId)
+	require.Nil(t, err)
+	require.Equal(t, *entityId, user.UserId)
+
+	apiResponse, err = WhenWeDeleteUserAsDryRun(ulid.Make().String(), &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 404, apiResponse.StatusCode)
+}
@@ -1,6 +1,7 @@
 package integrationtest
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -16,55 +17,72 @@ func init() {
 	cfgu.InitLambda(Fixture.DynamoDbStore)
 }
 
-func Test_Get_User_Should_Fail_When_Role_Missing(t *testing.T) {
+func Test_Get_User_Should_Return_Public_Profile_When_Role_Missing(t *testing.T) {
 	role := "cf:fake:role"
-	userId := ulid.Make().String()
 
-	apiResponse, err := WhenWeGetUser(userId, &role, nil)
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeGetUser(*entityId, &role, nil)
 	require.Nil(t, err)
-	require.Equal(t, 403, apiResponse.StatusCode)
-	require.Contains(t, apiResponse.Body, "You do not have the appropriate permissions to perform this action. Please check the appropriate documentation to ensure you have the correct permissions.")
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	var user = GetDataFromResponse[cfm.UserResponse](apiResponse)
+
+	require.Equal(t, *entityId, user.UserId)
+	require.Equal(t, args.Username, user.Username)
+	require.Equal(t, args.FirstName, user.FirstName)
+	require.Equal(t, args.ProfileImageId, user.ProfileImageId)
+	require.Equal(t, args.Biography, user.Biography)
+	require.Nil(t, user.LastName)
+	require.Nil(t, user.PhoneNumber)
+	require.Nil(t, user.EmailAddress)
+	require.Nil(t, user.PrimaryAddress)
+	require.Nil(t, user.BillingAddress)
+	require.NotContains(t, apiResponse.Body, "omitempty")
 }
 
 func Test_Get_User_Should_Succeed(t *testing.T) {
 	startTime := time.Now()
 	role := cfe.ReadUser.String()
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	args := GivenCreateUserArgs(nil)
-	entityId, err := Fixture.DynamoDbStore.CreateUser(args)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	apiResponse, err := WhenWeGetUser(*entityId, &role, nil)
 	require.Nil(t, err)
 	require.Equal(t, 200, apiResponse.StatusCode)
 
-	var user = GetDataFromResponse[cfm.User](apiResponse)
+	var user = GetDataFromResponse[cfm.UserResponse](apiResponse)
 
 	require.Equal(t, *entityId, user.UserId)
 	require.Equal(t, args.Username, user.Username)
 	require.Equal(t, args.FirstName, user.FirstName)
 	require.Equal(t, args.LastName, user.LastName)
 	require.Equal(t, args.PhoneNumber, user.PhoneNumber)
-	require.Equal(t, args.EmailAddress, user.EmailAddress)
+	require.Equal(t, args.EmailAddress, *user.EmailAddress)
 	require.Equal(t, args.PrimaryAddress, user.PrimaryAddress)
 	require.Equal(t, args.BillingAddress, user.BillingAddress)
 	require.Equal(t, args.ProfileImageId, user.ProfileImageId)
 	require.Equal(t, args.Biography, user.Biography)
-	require.Equal(t, args.AccountType, user.AccountType)
-	require.WithinRange(t, user.CreatedDate, startTime, time.Now())
-	require.Equal(t, user.CreatedDate, user.UpdatedDate)
+	require.Equal(t, args.AccountType, *user.AccountType)
+	require.WithinRange(t, *user.CreatedDate, startTime, time.Now())
+	require.Equal(t, *user.CreatedDate, *user.UpdatedDate)
 }
 
 func Test_Get_User_Should_Succeed_By_Email_Or_Username(t *testing.T) {
 	role := cfe.ReadUser.String()
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	args := GivenCreateUserArgs(nil)
-	entityId, err := Fixture.DynamoDbStore.CreateUser(args)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	// Get User by Email
@@ -72,7 +90,7 @@ func Test_Get_User_Should_Succeed_By_Email_Or_Username(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 200, apiResponse.StatusCode)
 
-	user := GetDataFromResponse[cfm.User](apiResponse)
+	user := GetDataFromResponse[cfm.UserResponse](apiResponse)
 
 	require.Equal(t, *entityId, user.UserId)
 
@@ -81,7 +99,159 @@ func Test_Get_User_Should_Succeed_By_Email_Or_Username(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 200, apiResponse.StatusCode)
 
-	user = GetDataFromResponse[cfm.User](apiResponse)
+	user = GetDataFromResponse[cfm.UserResponse](apiResponse)
 
 	require.Equal(t, *entityId, user.UserId)
 }
+
+func Test_Get_User_Should_Return_Not_Found_By_Email_Or_Username_When_Role_Missing(t *testing.T) {
+	role := "cf:fake:role"
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	args := GivenCreateUserArgs(nil)
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeGetUser(args.EmailAddress, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 404, apiResponse.StatusCode)
+	require.NotContains(t, apiResponse.Body, args.Username)
+
+	apiResponse, err = WhenWeGetUser(args.Username, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 404, apiResponse.StatusCode)
+}
+
+func Test_Get_User_Should_Return_Not_Found_For_Unknown_Email_When_Role_Missing(t *testing.T) {
+	role := "cf:fake:role"
+	email := "user" + ulid.Make().String() + "@canary-classifind.com"
+
+	apiResponse, err := WhenWeGetUser(email, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 404, apiResponse.StatusCode)
+
+	apiResponse, err = WhenWeGetUser("unknown"+ulid.Make().String(), &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 404, apiResponse.StatusCode)
+}
+
+func Test_Get_User_Should_Succeed_By_Email_For_Owner(t *testing.T) {
+	role := "cf:fake:role"
+	requesterId := ulid.Make().String()
+
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
+
+	args := GivenCreateUserArgs(nil)
+	args.IdentitySubject = &requesterId
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeGetUser(args.EmailAddress, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	user := GetDataFromResponse[cfm.UserResponse](apiResponse)
+	require.Equal(t, *entityId, user.UserId)
+	require.Equal(t, args.EmailAddress, *user.EmailAddress)
+}
+
+func Test_Get_User_Should_Return_Not_Modified_For_Matching_ETag(t *testing.T) {
+	role := cfe.ReadUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeGetUser(*entityId, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+	require.Equal(t, "private, no-cache", apiResponse.Headers["Cache-Control"])
+	require.NotEmpty(t, apiResponse.Headers["Last-Modified"])
+
+	etag := apiResponse.Headers["ETag"]
+	require.NotEmpty(t, etag)
+
+	apiResponse, err = WhenWeGetUserWithHeaders(*entityId, map[string]string{"If-None-Match": etag}, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 304, apiResponse.StatusCode)
+	require.Empty(t, apiResponse.Body)
+	require.Equal(t, etag, apiResponse.Headers["ETag"])
+}
+
+func Test_Get_User_Should_Return_Selected_Fields(t *testing.T) {
+	role := cfe.ReadUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeGetUserWithFields(*entityId, "username,firstName", &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	var user = GetDataFromResponse[cfm.UserResponse](apiResponse)
+
+	require.Equal(t, *entityId, user.UserId)
+	require.Equal(t, args.Username, user.Username)
+	require.Equal(t, args.FirstName, user.FirstName)
+	require.Nil(t, user.EmailAddress)
+	require.Nil(t, user.PrimaryAddress)
+	require.Nil(t, user.Biography)
+}
+
+func Test_Get_User_Should_Reject_Unknown_Fields(t *testing.T) {
+	role := cfe.ReadUser.String()
+
+	apiResponse, err := WhenWeGetUserWithFields(ulid.Make().String(), "username,GSI1PK", &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 400, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, `"field":"Fields[1]"`)
+}
+
+func Test_Get_User_Should_Return_Consumed_Capacity(t *testing.T) {
+	role := cfe.ReadUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	apiResponse, err := WhenWeGetUser(*entityId, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+	require.Regexp(t, `^read=[0-9.]+, write=[0-9.]+, total=[0-9.]+$`, apiResponse.Headers["X-Consumed-Capacity"])
+	require.NotEqual(t, "read=0, write=0, total=0", apiResponse.Headers["X-Consumed-Capacity"])
+}
+
+func Test_Get_User_Should_Return_Too_Many_Requests_When_Bucket_Is_Empty(t *testing.T) {
+	role := cfe.ReadUser.String()
+	requesterId := ulid.Make().String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	// The first call writes the caller's bucket, proving the handler may store it
+	apiResponse, err := WhenWeGetUser(*entityId, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+	require.Equal(t, "99", apiResponse.Headers["RateLimit-Remaining"])
+
+	bucket, err := Fixture.DynamoDbStore.GetRateLimitBucket(context.TODO(), "RATELIMIT#"+requesterId, "RATELIMIT#"+role)
+	require.Nil(t, err)
+	require.NotNil(t, bucket)
+
+	// Empty the bucket as if the caller had used up their burst
+	previousUpdatedAt := bucket.UpdatedAt
+	bucket.Tokens = 0
+	bucket.UpdatedAt = time.Now().UnixMilli()
+	saved, err := Fixture.DynamoDbStore.SaveRateLimitBucket(context.TODO(), bucket, &previousUpdatedAt)
+	require.Nil(t, err)
+	require.True(t, *saved)
+
+	apiResponse, err = WhenWeGetUser(*entityId, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 429, apiResponse.StatusCode)
+	require.NotEmpty(t, apiResponse.Headers["Retry-After"])
+	require.Equal(t, "0", apiResponse.Headers["RateLimit-Remaining"])
+}
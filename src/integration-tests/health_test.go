@@ -0,0 +1,44 @@
+package integrationtest
+
+import (
+	"testing"
+
+	cfc "cf-user/core"
+	cfm "cf-user/core/models"
+	cfhc "cf-user/health-check"
+
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	cfhc.InitLambda(Fixture.DynamoDbStore)
+}
+
+func Test_Get_Health_Should_Pass_Without_Authentication(t *testing.T) {
+	apiResponse, err := WhenWeGetHealth(false)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+	require.Equal(t, cfc.MediaTypeHealthJson, apiResponse.Headers["Content-Type"])
+	require.Equal(t, "no-store", apiResponse.Headers["Cache-Control"])
+
+	health := GetDataFromResponse[cfm.HealthResponse](apiResponse)
+	require.Equal(t, "pass", health.Status)
+	require.Equal(t, cfc.BuildVersion, health.ReleaseId)
+	require.Empty(t, health.Checks)
+}
+
+func Test_Get_Deep_Health_Should_Check_Table_And_Indexes(t *testing.T) {
+	apiResponse, err := WhenWeGetHealth(true)
+	require.Nil(t, err)
+	require.Equal(t, 200, apiResponse.StatusCode)
+
+	health := GetDataFromResponse[cfm.HealthResponse](apiResponse)
+	require.Equal(t, "pass", health.Status)
+	require.Len(t, health.Checks["dynamodb:describeTable"], 4)
+	require.Len(t, health.Checks["dynamodb:responseTime"], 4)
+
+	for _, check := range health.Checks["dynamodb:responseTime"] {
+		require.Equal(t, "pass", check.Status, check.ComponentId)
+		require.Equal(t, "ms", check.ObservedUnit)
+	}
+}
@@ -1,6 +1,7 @@
 package integrationtest
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -37,10 +38,10 @@ func Test_Update_User_Should_Succeed(t *testing.T) {
 	email := username + "@canary-classifind.com"
 	role := cfe.UpdateUser.String()
 
-	Fixture.DynamoDbStore.WipeTestData()
+	Fixture.DynamoDbStore.WipeTestData(context.TODO())
 
 	args := GivenCreateUserArgs(&email)
-	entityId, err := Fixture.DynamoDbStore.CreateUser(args)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
 	require.Nil(t, err)
 
 	request := GivenUpdateUserRequest(nil)
@@ -49,7 +50,7 @@ func Test_Update_User_Should_Succeed(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 204, apiResponse.StatusCode)
 
-	user, err := Fixture.DynamoDbStore.GetUser(*entityId)
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), *entityId)
 	require.Nil(t, err)
 
 	accountType := user.AccountType.String()
@@ -75,3 +76,188 @@ func Test_Update_User_Should_Succeed(t *testing.T) {
 	require.WithinRange(t, user.CreatedDate, startTime, time.Now())
 	require.True(t, user.UpdatedDate.After(user.CreatedDate))
 }
+
+func Test_Update_User_Should_Reject_Admin_Fields_Without_Admin_Role(t *testing.T) {
+	email := "user" + ulid.Make().String() + "@canary-classifind.com"
+	role := cfe.UpdateUser.String()
+
+	args := GivenCreateUserArgs(&email)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	accountType := cfe.BusinessAccount.String()
+	request := GivenUpdateUserRequest(nil)
+	request.AccountType = &accountType
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 403, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, `"field":"accountType"`)
+
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), *entityId)
+	require.Nil(t, err)
+	require.Equal(t, args.AccountType, user.AccountType)
+}
+
+func Test_Update_User_Should_Update_Admin_Fields_With_Admin_Role(t *testing.T) {
+	email := "user" + ulid.Make().String() + "@canary-classifind.com"
+	role := cfe.UpdateUser.String() + "," + cfe.AdminUser.String()
+
+	args := GivenCreateUserArgs(&email)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	username := "admin" + ulid.Make().String()
+	accountType := cfe.BusinessAccount.String()
+	request := GivenUpdateUserRequest(nil)
+	request.Username = &username
+	request.AccountType = &accountType
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), *entityId)
+	require.Nil(t, err)
+	require.Equal(t, username, user.Username)
+	require.Equal(t, cfe.BusinessAccount, user.AccountType)
+	require.Equal(t, fmt.Sprintf("USERNAME#%v", username), *user.GSI1PK)
+}
+
+func Test_Update_User_Should_Allow_Unchanged_Admin_Fields_For_Owner(t *testing.T) {
+	role := "cf:fake:role"
+	requesterId := ulid.Make().String()
+
+	args := GivenCreateUserArgs(nil)
+	args.IdentitySubject = &requesterId
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	accountType := args.AccountType.String()
+	request := GivenUpdateUserRequest(nil)
+	request.Username = &args.Username
+	request.AccountType = &accountType
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+
+	// Changing the username still needs cf:admin:user
+	username := "owner" + ulid.Make().String()
+	request.Username = &username
+
+	apiResponse, err = WhenWeUpdateUser(*entityId, request, &role, &requesterId)
+	require.Nil(t, err)
+	require.Equal(t, 403, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, `"field":"username"`)
+	require.NotContains(t, apiResponse.Body, `"field":"accountType"`)
+}
+
+func Test_Rename_User_Should_Reject_Claimed_Username(t *testing.T) {
+	claimed := GivenCreateUserArgs(nil)
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), claimed)
+	require.Nil(t, err)
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	previousUsername := args.Username
+	args.Username = claimed.Username
+
+	// Calls the store directly, as a rename racing the handler's lookup would
+	_, err = Fixture.DynamoDbStore.RenameUser(context.TODO(), *entityId, previousUsername, args)
+	require.NotNil(t, err)
+
+	var respError cfe.ResponseError
+	require.ErrorAs(t, err, &respError)
+	require.Equal(t, 400, respError.ErrorStatus)
+
+	user, err := Fixture.DynamoDbStore.GetUser(context.TODO(), *entityId)
+	require.Nil(t, err)
+	require.Equal(t, previousUsername, user.Username)
+}
+
+func Test_Rename_User_Should_Free_Previous_Username(t *testing.T) {
+	role := cfe.UpdateUser.String() + "," + cfe.AdminUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	renamed := "renamed" + ulid.Make().String()
+	request := GivenUpdateUserRequest(nil)
+	request.Username = &renamed
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+
+	other := GivenCreateUserArgs(nil)
+	other.Username = args.Username
+	_, err = Fixture.DynamoDbStore.CreateUser(context.TODO(), other)
+	require.Nil(t, err)
+}
+
+func Test_Update_User_Should_Link_Identity_Subject_With_Admin_Role(t *testing.T) {
+	role := cfe.UpdateUser.String() + "," + cfe.AdminUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	subject := ulid.Make().String()
+	request := GivenUpdateUserRequest(nil)
+	request.IdentitySubject = &subject
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 204, apiResponse.StatusCode)
+
+	user, err := Fixture.DynamoDbStore.GetUserByIdentitySubject(context.TODO(), subject)
+	require.Nil(t, err)
+	require.Equal(t, *entityId, user.UserId)
+}
+
+func Test_Update_User_Should_Reject_Linked_Identity_Subject(t *testing.T) {
+	role := cfe.UpdateUser.String() + "," + cfe.AdminUser.String()
+	subject := ulid.Make().String()
+
+	linked := GivenCreateUserArgs(nil)
+	linked.IdentitySubject = &subject
+	_, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), linked)
+	require.Nil(t, err)
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	request := GivenUpdateUserRequest(nil)
+	request.IdentitySubject = &subject
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 400, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, "User already exists for the given identity subject.")
+
+	// Calls the store directly, as a link racing the handler's lookup would
+	_, err = Fixture.DynamoDbStore.LinkIdentitySubject(context.TODO(), *entityId, nil, subject)
+	require.NotNil(t, err)
+}
+
+func Test_Update_User_Should_Require_Admin_Role_To_Link_Identity_Subject(t *testing.T) {
+	role := cfe.UpdateUser.String()
+
+	args := GivenCreateUserArgs(nil)
+	entityId, err := Fixture.DynamoDbStore.CreateUser(context.TODO(), args)
+	require.Nil(t, err)
+
+	subject := ulid.Make().String()
+	request := GivenUpdateUserRequest(nil)
+	request.IdentitySubject = &subject
+
+	apiResponse, err := WhenWeUpdateUser(*entityId, request, &role, nil)
+	require.Nil(t, err)
+	require.Equal(t, 403, apiResponse.StatusCode)
+	require.Contains(t, apiResponse.Body, "identitySubject")
+}
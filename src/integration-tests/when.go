@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 
+	cfc "cf-user/core"
 	cfcu "cf-user/create-user"
 	cfdu "cf-user/delete-user"
+	cfgcu "cf-user/get-current-user"
 	cfgu "cf-user/get-user"
+	cfhc "cf-user/health-check"
+	cfucu "cf-user/update-current-user"
 	cfuu "cf-user/update-user"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -20,12 +24,52 @@ func WhenWeGetUser(userId string, permissions *string, requesterId *string) (eve
 	return cfgu.Handler(context.TODO(), *apiRequest)
 }
 
+func WhenWeGetUserWithFields(userId string, fields string, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	apiRequest := CreateGetRequest(permissions, requesterId)
+	apiRequest.PathParameters["userId"] = userId
+	apiRequest.QueryStringParameters["fields"] = fields
+
+	return cfgu.Handler(context.TODO(), *apiRequest)
+}
+
+func WhenWeGetUserWithHeaders(userId string, headers map[string]string, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	apiRequest := CreateGetRequest(permissions, requesterId)
+	apiRequest.PathParameters["userId"] = userId
+	for name, value := range headers {
+		apiRequest.Headers[name] = value
+	}
+
+	return cfgu.Handler(context.TODO(), *apiRequest)
+}
+
 func WhenWeCreateUser(request *cfcu.CreateUserRequest, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
 	apiRequest := createPostRequest(request, permissions, requesterId)
 
 	return cfcu.Handler(context.TODO(), *apiRequest)
 }
 
+func WhenWeCreateUserAsDryRun(request *cfcu.CreateUserRequest, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	apiRequest := createPostRequest(request, permissions, requesterId)
+	apiRequest.Headers["Prefer"] = "dry-run"
+
+	return cfcu.Handler(context.TODO(), *apiRequest)
+}
+
+func WhenWeDeleteUserAsDryRun(userId string, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	apiRequest := createDeleteRequest(permissions, requesterId)
+	apiRequest.PathParameters["userId"] = userId
+	apiRequest.QueryStringParameters["dryRun"] = "true"
+
+	return cfdu.Handler(context.TODO(), *apiRequest)
+}
+
+func WhenWeCreateUserIdempotently(request *cfcu.CreateUserRequest, idempotencyKey string, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	apiRequest := createPostRequest(request, permissions, requesterId)
+	apiRequest.Headers[cfc.IdempotencyKeyHeader] = idempotencyKey
+
+	return cfcu.Handler(context.TODO(), *apiRequest)
+}
+
 func WhenWeUpdateUser(userId string, request *cfuu.UpdateUserRequest, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
 	apiRequest := createPutRequest(request, permissions, requesterId)
 	apiRequest.PathParameters["userId"] = userId
@@ -33,6 +77,27 @@ func WhenWeUpdateUser(userId string, request *cfuu.UpdateUserRequest, permission
 	return cfuu.Handler(context.TODO(), *apiRequest)
 }
 
+func WhenWeGetCurrentUser(permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	apiRequest := CreateGetRequest(permissions, requesterId)
+
+	return cfgcu.Handler(context.TODO(), *apiRequest)
+}
+
+func WhenWeUpdateCurrentUser(request *cfuu.UpdateUserRequest, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
+	currentUserRequest := &cfucu.UpdateCurrentUserRequest{
+		FirstName:      request.FirstName,
+		LastName:       request.LastName,
+		PhoneNumber:    request.PhoneNumber,
+		PrimaryAddress: request.PrimaryAddress,
+		BillingAddress: request.BillingAddress,
+		ProfileImageId: request.ProfileImageId,
+		Biography:      request.Biography,
+	}
+	apiRequest := createPutRequest(currentUserRequest, permissions, requesterId)
+
+	return cfucu.Handler(context.TODO(), *apiRequest)
+}
+
 func WhenWeDeleteUser(userId string, permissions *string, requesterId *string) (events.APIGatewayProxyResponse, error) {
 	apiRequest := createDeleteRequest(permissions, requesterId)
 	apiRequest.PathParameters["userId"] = userId
@@ -41,6 +106,17 @@ func WhenWeDeleteUser(userId string, permissions *string, requesterId *string) (
 }
 
 // Helper Functions
+// Health
+func WhenWeGetHealth(deep bool) (events.APIGatewayProxyResponse, error) {
+	apiRequest := CreateGetRequest(nil, nil)
+	apiRequest.Resource = "/v1/health"
+	if deep {
+		apiRequest.Resource = "/v1/health/deep"
+	}
+
+	return cfhc.Handler(context.TODO(), *apiRequest)
+}
+
 func createPostRequest[T interface{}](body T, permissions *string, requesterId *string) *events.APIGatewayProxyRequest {
 	apiRequest := createRequest("POST", permissions, requesterId)
 
@@ -90,6 +166,7 @@ func createRequest(httpMethod string, permissions *string, requesterId *string)
 		apiRequest.RequestContext.Authorizer["requesterOid"] = *requesterId
 	}
 
+	apiRequest.Headers = make(map[string]string)
 	apiRequest.PathParameters = make(map[string]string)
 	apiRequest.QueryStringParameters = make(map[string]string)
 
@@ -0,0 +1,140 @@
+package unittest
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfconfig "cf-user/core/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeParameterClient struct {
+	parameters map[string]string
+}
+
+func (client fakeParameterClient) GetParametersByPath(ctx context.Context, input *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	output := &ssm.GetParametersByPathOutput{}
+	for name, value := range client.parameters {
+		output.Parameters = append(output.Parameters, types.Parameter{Name: aws.String(*input.Path + "/" + name), Value: aws.String(value)})
+	}
+
+	return output, nil
+}
+
+func Test_Config_Should_Load_Settings_With_Defaults(t *testing.T) {
+	config, err := cfconfig.Load(context.TODO(), cfconfig.MapSource{
+		"SERVICE":         "cf-user",
+		"STAGE":           "dev",
+		"USER_TABLE_NAME": "cf-user-dev-app-user",
+	})
+	require.Nil(t, err)
+
+	require.Equal(t, "cf-user", config.Service)
+	require.Equal(t, "dev", config.Stage)
+	require.Equal(t, "cf-user-dev-app-user", config.UserTableName)
+	require.Equal(t, "cf-user", config.MetricsNamespace)
+	require.Equal(t, time.Minute, config.FeatureFlagsTtl)
+	require.Equal(t, slog.LevelInfo, config.LogLevel)
+}
+
+func Test_Config_Should_Fail_When_Settings_Are_Invalid(t *testing.T) {
+	_, err := cfconfig.Load(context.TODO(), cfconfig.MapSource{
+		"SERVICE": "cf-user",
+		"STAGE":   "dev",
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "USER_TABLE_NAME failed required")
+
+	_, err = cfconfig.Load(context.TODO(), cfconfig.MapSource{
+		"SERVICE":              "cf-user",
+		"STAGE":                "dev",
+		"USER_TABLE_NAME":      "cf-user-dev-app-user",
+		"OTEL_TRACES_EXPORTER": "zipkin",
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "OTEL_TRACES_EXPORTER failed oneof")
+
+	_, err = cfconfig.Load(context.TODO(), cfconfig.MapSource{
+		"SERVICE":           "cf-user",
+		"STAGE":             "dev",
+		"USER_TABLE_NAME":   "cf-user-dev-app-user",
+		"FEATURE_FLAGS_TTL": "soon",
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid config setting FEATURE_FLAGS_TTL")
+
+	_, err = cfconfig.Load(context.TODO(), cfconfig.MapSource{
+		"SERVICE":         "cf-user",
+		"STAGE":           "dev",
+		"USER_TABLE_NAME": "cf-user-dev-app-user",
+		"LOG_LEVEL":       "verbose",
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid config setting LOG_LEVEL")
+}
+
+func Test_Config_Should_Prefer_Later_Sources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"SERVICE": "cf-user",
+		"STAGE": "dev",
+		"USER_TABLE_NAME": "file-table",
+		"METRICS_NAMESPACE": "file-namespace",
+		"RATE_LIMITS": {"cf:read:user": {"capacity": 50, "refillPerSecond": 5}}
+	}`), 0600)
+	require.Nil(t, err)
+
+	config, err := cfconfig.Load(context.TODO(),
+		cfconfig.FileSource{Path: path},
+		&cfconfig.SsmSource{Path: "/cf-user/dev/config", Client: fakeParameterClient{parameters: map[string]string{"USER_TABLE_NAME": "ssm-table"}}},
+		cfconfig.MapSource{"METRICS_NAMESPACE": "env-namespace"},
+	)
+	require.Nil(t, err)
+
+	require.Equal(t, "ssm-table", config.UserTableName)
+	require.Equal(t, "env-namespace", config.MetricsNamespace)
+	require.JSONEq(t, `{"cf:read:user":{"capacity":50,"refillPerSecond":5}}`, config.RateLimits)
+}
+
+func Test_Config_Should_Fail_When_File_Is_Missing(t *testing.T) {
+	_, err := cfconfig.Load(context.TODO(), cfconfig.FileSource{Path: filepath.Join(t.TempDir(), "missing.json")})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "unable to read config file")
+}
+
+func Test_AuthConfig_Should_Derive_Jwks_Url_From_Issuer(t *testing.T) {
+	config, err := cfconfig.LoadAuth(context.TODO(), cfconfig.MapSource{
+		"AUTH_ISSUER":   "https://tenant.auth0.com/",
+		"AUTH_AUDIENCE": "https://api.classifind.app",
+	})
+	require.Nil(t, err)
+
+	require.Equal(t, "https://tenant.auth0.com/.well-known/jwks.json", config.JwksUrl)
+	require.Equal(t, time.Hour, config.JwksTtl)
+	require.Equal(t, "permissions", config.PermissionsClaim)
+
+	config, err = cfconfig.LoadAuth(context.TODO(), cfconfig.MapSource{
+		"AUTH_ISSUER":            "https://tenant.auth0.com/",
+		"AUTH_AUDIENCE":          "https://api.classifind.app",
+		"AUTH_JWKS_URL":          "https://keys.example.com/jwks.json",
+		"AUTH_PERMISSIONS_CLAIM": "https://classifind.app/permissions",
+	})
+	require.Nil(t, err)
+	require.Equal(t, "https://keys.example.com/jwks.json", config.JwksUrl)
+	require.Equal(t, "https://classifind.app/permissions", config.PermissionsClaim)
+}
+
+func Test_AuthConfig_Should_Fail_When_Settings_Are_Missing(t *testing.T) {
+	_, err := cfconfig.LoadAuth(context.TODO(), cfconfig.MapSource{"AUTH_ISSUER": "not a url"})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "AUTH_ISSUER failed url")
+	require.Contains(t, err.Error(), "AUTH_AUDIENCE failed required")
+}
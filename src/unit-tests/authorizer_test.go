@@ -0,0 +1,208 @@
+package unittest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cfa "cf-user/authorizer"
+	cfconfig "cf-user/core/config"
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://auth.example.com/"
+const testAudience = "https://api.example.com"
+const testMethodArn = "arn:aws:execute-api:us-west-2:123456789012:abcdef1234/LIVE/GET/v1/users/123"
+
+type authorizerFixture struct {
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	server     *httptest.Server
+	requests   atomic.Int32
+	authorizer *cfa.Authorizer
+}
+
+func givenAuthorizer(t *testing.T) *authorizerFixture {
+	fixture := &authorizerFixture{}
+
+	var err error
+	fixture.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	fixture.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	encode := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.Bytes())
+	}
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kid": "rsa-key", "kty": "RSA", "use": "sig", "alg": "RS256", "n": encode(fixture.rsaKey.N), "e": encode(big.NewInt(int64(fixture.rsaKey.E)))},
+			{"kid": "ec-key", "kty": "EC", "use": "sig", "alg": "ES256", "crv": "P-256", "x": encode(fixture.ecKey.X), "y": encode(fixture.ecKey.Y)},
+		},
+	}
+
+	fixture.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture.requests.Add(1)
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(fixture.server.Close)
+
+	fixture.authorizer, err = cfa.CreateAuthorizer(cfa.Config{
+		Issuer:           testIssuer,
+		Audience:         testAudience,
+		JwksUrl:          fixture.server.URL,
+		JwksTtl:          time.Hour,
+		PermissionsClaim: "permissions",
+	}, fixture.server.Client())
+	require.Nil(t, err)
+
+	return fixture
+}
+
+func givenClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":         testIssuer,
+		"aud":         testAudience,
+		"sub":         "auth0|user-123",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"scope":       "openid cf:read:user",
+		"permissions": []string{"cf:update:user"},
+	}
+}
+
+func (fixture *authorizerFixture) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	var key interface{} = fixture.rsaKey
+	if method == jwt.SigningMethodES256 {
+		key = fixture.ecKey
+	}
+
+	signed, err := token.SignedString(key)
+	require.Nil(t, err)
+
+	return signed
+}
+
+func (fixture *authorizerFixture) authorize(token string) (events.APIGatewayCustomAuthorizerResponse, error) {
+	return fixture.authorizer.Authorize(context.TODO(), events.APIGatewayCustomAuthorizerRequest{
+		Type:               "TOKEN",
+		AuthorizationToken: "Bearer " + token,
+		MethodArn:          testMethodArn,
+	})
+}
+
+func Test_Authorizer_Should_Allow_Valid_RS256_Token(t *testing.T) {
+	fixture := givenAuthorizer(t)
+
+	response, err := fixture.authorize(fixture.sign(t, jwt.SigningMethodRS256, "rsa-key", givenClaims()))
+
+	require.Nil(t, err)
+	require.Equal(t, "auth0|user-123", response.PrincipalID)
+	require.Equal(t, "Allow", response.PolicyDocument.Statement[0].Effect)
+	require.Equal(t, []string{"arn:aws:execute-api:us-west-2:123456789012:abcdef1234/LIVE/*/*"}, response.PolicyDocument.Statement[0].Resource)
+	require.Equal(t, "auth0|user-123", response.Context["requesterOid"])
+	require.Equal(t, "cf:read:user,cf:update:user,openid", response.Context["permissions"])
+
+	require.True(t, cfe.ReadUser.ExistsInAuthContext(response.Context))
+	require.True(t, cfe.UpdateUser.ExistsInAuthContext(response.Context))
+	require.False(t, cfe.DeleteUser.ExistsInAuthContext(response.Context))
+}
+
+func Test_Authorizer_Should_Allow_Valid_ES256_Token(t *testing.T) {
+	fixture := givenAuthorizer(t)
+
+	response, err := fixture.authorize(fixture.sign(t, jwt.SigningMethodES256, "ec-key", givenClaims()))
+
+	require.Nil(t, err)
+	require.Equal(t, "auth0|user-123", response.Context["requesterOid"])
+}
+
+func Test_Authorizer_Should_Cache_Jwks(t *testing.T) {
+	fixture := givenAuthorizer(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := fixture.authorize(fixture.sign(t, jwt.SigningMethodRS256, "rsa-key", givenClaims()))
+		require.Nil(t, err)
+	}
+
+	require.Equal(t, int32(1), fixture.requests.Load())
+}
+
+func Test_Authorizer_Should_Reject_Invalid_Tokens(t *testing.T) {
+	fixture := givenAuthorizer(t)
+
+	expired := givenClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	wrongAudience := givenClaims()
+	wrongAudience["aud"] = "https://other.example.com"
+
+	wrongIssuer := givenClaims()
+	wrongIssuer["iss"] = "https://evil.example.com/"
+
+	missingSubject := givenClaims()
+	delete(missingSubject, "sub")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, givenClaims())
+	forged.Header["kid"] = "rsa-key"
+	forgedToken, err := forged.SignedString(otherKey)
+	require.Nil(t, err)
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, givenClaims()).SignedString([]byte("secret"))
+	require.Nil(t, err)
+
+	tokens := map[string]string{
+		"expired":         fixture.sign(t, jwt.SigningMethodRS256, "rsa-key", expired),
+		"wrong audience":  fixture.sign(t, jwt.SigningMethodRS256, "rsa-key", wrongAudience),
+		"wrong issuer":    fixture.sign(t, jwt.SigningMethodRS256, "rsa-key", wrongIssuer),
+		"missing subject": fixture.sign(t, jwt.SigningMethodRS256, "rsa-key", missingSubject),
+		"unknown kid":     fixture.sign(t, jwt.SigningMethodRS256, "other-key", givenClaims()),
+		"forged":          forgedToken,
+		"hmac":            hmacToken,
+		"malformed":       "not-a-token",
+	}
+
+	for name, token := range tokens {
+		_, err := fixture.authorize(token)
+		require.Equal(t, cfa.ErrUnauthorized, err, name)
+	}
+
+	_, err = fixture.authorizer.Authorize(context.TODO(), events.APIGatewayCustomAuthorizerRequest{MethodArn: testMethodArn})
+	require.Equal(t, cfa.ErrUnauthorized, err)
+}
+
+func Test_Authorizer_Should_Load_Injected_Config(t *testing.T) {
+	settings, err := cfconfig.LoadAuth(context.TODO(), cfconfig.MapSource{
+		"AUTH_ISSUER":   testIssuer,
+		"AUTH_AUDIENCE": testAudience,
+	})
+	require.Nil(t, err)
+
+	cfconfig.UseAuth(settings)
+	t.Cleanup(func() { cfconfig.UseAuth(nil) })
+
+	config := cfa.LoadConfig()
+	require.Equal(t, testIssuer, config.Issuer)
+	require.Equal(t, testAudience, config.Audience)
+	require.Equal(t, "https://auth.example.com/.well-known/jwks.json", config.JwksUrl)
+	require.Equal(t, "permissions", config.PermissionsClaim)
+}
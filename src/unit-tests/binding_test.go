@@ -0,0 +1,101 @@
+package unittest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cfc "cf-user/core"
+	cfgu "cf-user/get-user"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+type bindingTestRequest struct {
+	UserId  string        `path:"userId"`
+	Limit   int           `query:"limit"`
+	Active  *bool         `query:"active"`
+	Fields  []string      `query:"fields"`
+	Ids     []int64       `query:"ids"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	IfMatch *string       `header:"If-Match"`
+	Name    string        `json:"name"`
+}
+
+func Test_BindRequest_Should_Bind_Typed_Parameters(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		PathParameters: map[string]string{"userId": "01J0000000000000000000000"},
+		QueryStringParameters: map[string]string{
+			"limit":   "25",
+			"active":  "true",
+			"fields":  "username,firstName",
+			"since":   "2024-06-01T12:00:00Z",
+			"timeout": "1500ms",
+		},
+		MultiValueQueryStringParameters: map[string][]string{
+			"ids": {"1", "2,3"},
+		},
+		Headers: map[string]string{"if-match": "\"abc\""},
+	}
+
+	request := bindingTestRequest{Name: "unchanged"}
+	bindingErrors := cfc.BindRequest(apiRequest, &request)
+
+	require.Nil(t, bindingErrors)
+	require.Equal(t, "01J0000000000000000000000", request.UserId)
+	require.Equal(t, 25, request.Limit)
+	require.True(t, *request.Active)
+	require.Equal(t, []string{"username", "firstName"}, request.Fields)
+	require.Equal(t, []int64{1, 2, 3}, request.Ids)
+	require.Equal(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), request.Since)
+	require.Equal(t, 1500*time.Millisecond, request.Timeout)
+	require.Equal(t, "\"abc\"", *request.IfMatch)
+	require.Equal(t, "unchanged", request.Name)
+}
+
+func Test_BindRequest_Should_Leave_Missing_Optional_Parameters_Unset(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		PathParameters: map[string]string{"userId": "jdoe"},
+	}
+
+	request := bindingTestRequest{}
+	bindingErrors := cfc.BindRequest(apiRequest, &request)
+
+	require.Nil(t, bindingErrors)
+	require.Equal(t, 0, request.Limit)
+	require.Nil(t, request.Active)
+	require.Nil(t, request.Fields)
+	require.Nil(t, request.IfMatch)
+}
+
+func Test_BindRequest_Should_Have_Errors_For_Missing_Path_And_Invalid_Values(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{
+			"limit":  "ten",
+			"active": "maybe",
+			"since":  "yesterday",
+		},
+	}
+
+	request := bindingTestRequest{}
+	bindingErrors := cfc.BindRequest(apiRequest, &request)
+
+	require.Len(t, bindingErrors, 4)
+
+	fields := make([]string, 0)
+	for _, err := range bindingErrors {
+		fields = append(fields, err.Field)
+	}
+	require.Equal(t, []string{"UserId", "Limit", "Active", "Since"}, fields)
+	require.Equal(t, "path parameter userId is required", bindingErrors[0].Error())
+	require.Equal(t, "query parameter limit must be an integer", bindingErrors[1].Error())
+}
+
+func Test_HasBindings_Should_Detect_Tagged_Request_Types(t *testing.T) {
+	require.True(t, cfc.HasBindings(reflect.TypeOf(cfgu.GetUserRequest{})))
+	require.True(t, cfc.HasBindings(reflect.TypeOf(&bindingTestRequest{})))
+	require.False(t, cfc.HasBindings(reflect.TypeOf(struct{ Name string }{})))
+	require.False(t, cfc.HasBindings(reflect.TypeOf("")))
+}
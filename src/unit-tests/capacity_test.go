@@ -0,0 +1,76 @@
+package unittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CapacityTracker_Should_Sum_Capacity_By_Operation_And_Index(t *testing.T) {
+	tracker := cfc.CreateCapacityTracker()
+
+	tracker.Add("GetUserByEmail", &types.ConsumedCapacity{
+		CapacityUnits:     aws.Float64(1),
+		ReadCapacityUnits: aws.Float64(1),
+		Table:             &types.Capacity{CapacityUnits: aws.Float64(0.5), ReadCapacityUnits: aws.Float64(0.5)},
+		GlobalSecondaryIndexes: map[string]types.Capacity{
+			"GSI2": {CapacityUnits: aws.Float64(0.5), ReadCapacityUnits: aws.Float64(0.5)},
+		},
+	})
+	tracker.Add("GetUserByEmail", &types.ConsumedCapacity{
+		CapacityUnits:     aws.Float64(0.5),
+		ReadCapacityUnits: aws.Float64(0.5),
+		Table:             &types.Capacity{CapacityUnits: aws.Float64(0.5), ReadCapacityUnits: aws.Float64(0.5)},
+	})
+	// Without an INDEXES breakdown the totals are attributed to the table
+	tracker.Add("CreateUser", &types.ConsumedCapacity{CapacityUnits: aws.Float64(2), WriteCapacityUnits: aws.Float64(2)})
+	tracker.Add("CreateUser", nil)
+
+	breakdown := tracker.Breakdown()
+	require.Equal(t, []cfc.ConsumedCapacityUsage{
+		{Operation: "CreateUser", Index: "table", WriteUnits: 2, CapacityUnits: 2},
+		{Operation: "GetUserByEmail", Index: "GSI2", ReadUnits: 0.5, CapacityUnits: 0.5},
+		{Operation: "GetUserByEmail", Index: "table", ReadUnits: 1, CapacityUnits: 1},
+	}, breakdown)
+
+	require.Equal(t, "read=1.5, write=2, total=3.5", tracker.HeaderValue())
+}
+
+func Test_CapacityTracker_Should_Be_Safe_When_Nil(t *testing.T) {
+	var tracker *cfc.CapacityTracker
+	tracker.Add("GetUser", &types.ConsumedCapacity{CapacityUnits: aws.Float64(1)})
+
+	require.Empty(t, tracker.Breakdown())
+	require.Equal(t, "read=0, write=0, total=0", tracker.HeaderValue())
+}
+
+func Test_CapacityTracker_Should_Report_Metrics_With_Requester(t *testing.T) {
+	tracker := cfc.CreateCapacityTracker()
+	tracker.Add("GetUser", &types.ConsumedCapacity{
+		Table: &types.Capacity{CapacityUnits: aws.Float64(0.5), ReadCapacityUnits: aws.Float64(0.5)},
+	})
+
+	var output bytes.Buffer
+	metrics := cfc.CreateMetrics("cf-user", &output, cfc.MetricDimension{Name: "Service", Value: "cf-user"})
+
+	tracker.Report(nil, metrics, "requester-123")
+	require.Nil(t, metrics.Flush())
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var record map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, "requester-123", record["RequesterOid"])
+	require.Equal(t, "GetUser", record["Operation"])
+	require.Equal(t, "table", record["Index"])
+	require.Equal(t, 0.5, record["ConsumedReadCapacity"])
+	require.Equal(t, 0.0, record["ConsumedWriteCapacity"])
+}
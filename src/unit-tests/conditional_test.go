@@ -0,0 +1,108 @@
+package unittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+const conditionalBody = `{"userId":"01J0000000000000000000000","username":"jdoe"}`
+
+var conditionalModified = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func whenWeGetConditionally(headers map[string]string) events.APIGatewayProxyResponse {
+	next := func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       conditionalBody,
+			Headers: map[string]string{
+				cfc.LastModifiedHeader: cfc.FormatHttpDate(conditionalModified),
+				cfc.CacheControlHeader: "public, max-age=60",
+			},
+		}
+	}
+
+	return cfc.ConditionalGetMiddleware()(next)(context.TODO(), events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Headers:    headers,
+	})
+}
+
+func Test_ConditionalGet_Should_Tag_Response_With_ETag(t *testing.T) {
+	response := whenWeGetConditionally(nil)
+
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, cfc.ComputeETag(conditionalBody), response.Headers[cfc.ETagHeader])
+	require.Equal(t, "Sat, 01 Jun 2024 12:00:00 GMT", response.Headers[cfc.LastModifiedHeader])
+}
+
+func Test_ConditionalGet_Should_Return_Not_Modified_For_Matching_ETag(t *testing.T) {
+	etag := cfc.ComputeETag(conditionalBody)
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		response := whenWeGetConditionally(map[string]string{"if-none-match": ifNoneMatch})
+
+		require.Equal(t, 304, response.StatusCode, ifNoneMatch)
+		require.Empty(t, response.Body)
+		require.Equal(t, etag, response.Headers[cfc.ETagHeader])
+		require.Equal(t, "public, max-age=60", response.Headers[cfc.CacheControlHeader])
+	}
+
+	response := whenWeGetConditionally(map[string]string{"If-None-Match": `"stale"`})
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, conditionalBody, response.Body)
+}
+
+func Test_ConditionalGet_Should_Honour_If_Modified_Since(t *testing.T) {
+	response := whenWeGetConditionally(map[string]string{"If-Modified-Since": cfc.FormatHttpDate(conditionalModified)})
+	require.Equal(t, 304, response.StatusCode)
+
+	response = whenWeGetConditionally(map[string]string{"If-Modified-Since": cfc.FormatHttpDate(conditionalModified.Add(-time.Minute))})
+	require.Equal(t, 200, response.StatusCode)
+
+	// If-None-Match takes precedence over If-Modified-Since
+	response = whenWeGetConditionally(map[string]string{
+		"If-None-Match":     `"stale"`,
+		"If-Modified-Since": cfc.FormatHttpDate(conditionalModified),
+	})
+	require.Equal(t, 200, response.StatusCode)
+}
+
+func Test_UserResponse_Should_Expose_Last_Modified_For_Public_View(t *testing.T) {
+	user := givenStoredUser()
+
+	response := cfm.CreateUserResponse(user, cfe.UserViewPublic)
+
+	require.Nil(t, response.UpdatedDate)
+	require.Equal(t, user.UpdatedDate, response.LastModified())
+	require.Equal(t, cfe.UserViewPublic, response.View)
+}
+
+func Test_VaryByCaller_Should_Vary_Cached_And_Not_Modified_Responses(t *testing.T) {
+	next := func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       conditionalBody,
+			Headers:    map[string]string{cfc.CacheControlHeader: "public, max-age=60", "Vary": "Accept"},
+		}
+	}
+	handler := cfc.VaryByCallerMiddleware()(cfc.ConditionalGetMiddleware()(next))
+
+	response := handler(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: "GET"})
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "Accept, Authorization", response.Headers["Vary"])
+
+	response = handler(context.TODO(), events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Headers:    map[string]string{"If-None-Match": response.Headers["ETag"]},
+	})
+	require.Equal(t, 304, response.StatusCode)
+	require.Equal(t, "Accept, Authorization", response.Headers["Vary"])
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
 )
 
 var Validate *validator.Validate
@@ -17,6 +18,7 @@ func init() {
 
 	// Register Customer Validation
 	Validate.RegisterValidation(cfe.GetAccountTypeValidator())
+	Validate.RegisterValidation(cfm.GetUserFieldValidator())
 }
 
 func AssertValidationError(t *testing.T, validationError error, fieldName string, tagName string) {
@@ -0,0 +1,60 @@
+package unittest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Correlation_Should_Reuse_Header_From_Request(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"x-correlation-id": "checkout-1234",
+		},
+	}
+
+	require.Equal(t, "checkout-1234", cfc.ResolveCorrelationId(apiRequest))
+}
+
+func Test_Correlation_Should_Generate_Ulid_When_Header_Missing_Or_Invalid(t *testing.T) {
+	requests := []events.APIGatewayProxyRequest{
+		{},
+		{Headers: map[string]string{"X-Correlation-Id": ""}},
+		{Headers: map[string]string{"X-Correlation-Id": "bad id\n{\"level\":\"ERROR\"}"}},
+	}
+
+	for _, apiRequest := range requests {
+		correlationId := cfc.ResolveCorrelationId(apiRequest)
+
+		_, err := ulid.ParseStrict(correlationId)
+		require.Nil(t, err)
+	}
+}
+
+func Test_Correlation_Should_Forward_Id_On_Outbound_Requests(t *testing.T) {
+	var received string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(cfc.CorrelationIdHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &cfc.CorrelationTransport{}}
+	ctx := cfc.ContextWithCorrelationId(context.TODO(), "checkout-1234")
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	response, err := client.Do(request)
+	require.Nil(t, err)
+	response.Body.Close()
+
+	require.Equal(t, "checkout-1234", received)
+	require.Equal(t, "", request.Header.Get(cfc.CorrelationIdHeader))
+}
@@ -0,0 +1,87 @@
+package unittest
+
+import (
+	"testing"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cors_Should_Match_Allowed_Origins(t *testing.T) {
+	policy, err := cfc.GetCorsPolicy("prod", "https://classifind.app, https://*.classifind.app")
+	require.Nil(t, err)
+
+	require.True(t, policy.AllowsOrigin("https://classifind.app"))
+	require.True(t, policy.AllowsOrigin("https://www.classifind.app"))
+	require.True(t, policy.AllowsOrigin("https://admin.eu.classifind.app"))
+	require.True(t, policy.AllowsOrigin("https://WWW.Classifind.app"))
+	require.False(t, policy.AllowsOrigin("http://www.classifind.app"))
+	require.False(t, policy.AllowsOrigin("https://evilclassifind.app"))
+	require.False(t, policy.AllowsOrigin("https://classifind.app.evil.com"))
+	require.False(t, policy.AllowsOrigin("null"))
+	require.False(t, policy.AllowsOrigin(""))
+}
+
+func Test_Cors_Should_Use_Stage_Defaults(t *testing.T) {
+	prod, err := cfc.GetCorsPolicy("prod", "")
+	require.Nil(t, err)
+	require.False(t, prod.AllowsOrigin("http://localhost:3000"))
+
+	dev, err := cfc.GetCorsPolicy("dev", "")
+	require.Nil(t, err)
+	require.True(t, dev.AllowsOrigin("http://localhost:3000"))
+
+	_, err = cfc.GetCorsPolicy("dev", "classifind.app")
+	require.NotNil(t, err)
+}
+
+func Test_Cors_Should_Apply_Headers_For_Allowed_Origin(t *testing.T) {
+	policy, err := cfc.GetCorsPolicy("prod", "")
+	require.Nil(t, err)
+
+	response := events.APIGatewayProxyResponse{
+		StatusCode: 400,
+		Headers:    map[string]string{"Vary": "Accept"},
+	}
+	policy.Apply(events.APIGatewayProxyRequest{Headers: map[string]string{"origin": "https://www.classifind.app"}}, &response)
+
+	require.Equal(t, "https://www.classifind.app", response.Headers["Access-Control-Allow-Origin"])
+	require.Equal(t, "true", response.Headers["Access-Control-Allow-Credentials"])
+	require.Contains(t, response.Headers["Access-Control-Expose-Headers"], "X-Correlation-Id")
+	require.Contains(t, response.Headers["Access-Control-Expose-Headers"], "ETag")
+	require.Equal(t, "600", response.Headers["Access-Control-Max-Age"])
+	require.Equal(t, "Accept, Origin", response.Headers["Vary"])
+}
+
+func Test_Cors_Should_Not_Allow_Credentials_For_Any_Origin(t *testing.T) {
+	policy, err := cfc.GetCorsPolicy("prod", "https://classifind.app, *")
+	require.Nil(t, err)
+	require.False(t, policy.AllowCredentials)
+
+	response := events.APIGatewayProxyResponse{StatusCode: 200}
+	policy.Apply(events.APIGatewayProxyRequest{Headers: map[string]string{"origin": "https://evil.example.com"}}, &response)
+
+	require.Equal(t, "*", response.Headers["Access-Control-Allow-Origin"])
+	require.NotContains(t, response.Headers, "Access-Control-Allow-Credentials")
+
+	// A policy built by hand with credentials still never pairs them with any origin
+	policy.AllowCredentials = true
+	response = events.APIGatewayProxyResponse{StatusCode: 200}
+	policy.Apply(events.APIGatewayProxyRequest{Headers: map[string]string{"origin": "https://classifind.app"}}, &response)
+
+	require.Equal(t, "*", response.Headers["Access-Control-Allow-Origin"])
+	require.NotContains(t, response.Headers, "Access-Control-Allow-Credentials")
+}
+
+func Test_Cors_Should_Not_Apply_Headers_For_Other_Origins(t *testing.T) {
+	policy, err := cfc.GetCorsPolicy("prod", "")
+	require.Nil(t, err)
+
+	response := events.APIGatewayProxyResponse{StatusCode: 200}
+	policy.Apply(events.APIGatewayProxyRequest{Headers: map[string]string{"Origin": "https://evil.com"}}, &response)
+
+	require.NotContains(t, response.Headers, "Access-Control-Allow-Origin")
+	require.Equal(t, "Origin", response.Headers["Vary"])
+}
@@ -0,0 +1,107 @@
+package unittest
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	cfc "cf-user/core"
+	cfuu "cf-user/update-user"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DecodeJson_Should_Ignore_Unknown_Fields_By_Default(t *testing.T) {
+	var request cfuu.UpdateUserRequest
+	err := cfc.DecodeJson([]byte(`{"firstName":"John","emailAddress":"john@example.com"}`), cfc.DecodeOptions{}, &request)
+
+	require.Nil(t, err)
+	require.Equal(t, "John", *request.FirstName)
+}
+
+func Test_DecodeJson_Should_Reject_Unknown_Fields_When_Strict(t *testing.T) {
+	var request cfuu.UpdateUserRequest
+	err := cfc.DecodeJson([]byte(`{"firstName":"John","emailAddress":"john@example.com"}`), cfc.StrictDecodeOptions(), &request)
+
+	require.NotNil(t, err)
+	require.Equal(t, "emailAddress", err.Field)
+	require.Equal(t, "unknown_field", err.Code)
+}
+
+func Test_DecodeJson_Should_Report_Path_Of_Nested_Unknown_Fields(t *testing.T) {
+	var request cfuu.UpdateUserRequest
+	err := cfc.DecodeJson([]byte(`{"FIRSTNAME":"John","billingAddress":{"city":"Provo"},"primaryAddress":{"city":"Provo","zip":"84601"}}`), cfc.StrictDecodeOptions(), &request)
+
+	require.NotNil(t, err)
+	require.Equal(t, "primaryAddress.zip", err.Field)
+	require.Equal(t, "unknown_field", err.Code)
+	require.Equal(t, "primaryAddress.zip: is not a recognised field", err.Error())
+}
+
+func Test_DecodeJson_Should_Reject_Duplicate_Keys_When_Strict(t *testing.T) {
+	var request cfuu.UpdateUserRequest
+	err := cfc.DecodeJson([]byte(`{"primaryAddress":{"city":"Provo","city":"Orem"}}`), cfc.StrictDecodeOptions(), &request)
+
+	require.NotNil(t, err)
+	require.Equal(t, "primaryAddress.city", err.Field)
+	require.Equal(t, "duplicate_key", err.Code)
+
+	err = cfc.DecodeJson([]byte(`{"primaryAddress":{"city":"Provo","city":"Orem"}}`), cfc.DecodeOptions{}, &request)
+
+	require.Nil(t, err)
+	require.Equal(t, "Orem", *request.PrimaryAddress.City)
+}
+
+func Test_DecodeJson_Should_Name_Field_With_Invalid_Type(t *testing.T) {
+	var request cfuu.UpdateUserRequest
+	err := cfc.DecodeJson([]byte(`{"primaryAddress":{"postalCode":84103}}`), cfc.StrictDecodeOptions(), &request)
+
+	require.NotNil(t, err)
+	require.Equal(t, "primaryAddress.postalCode", err.Field)
+	require.Equal(t, "invalid_type", err.Code)
+	require.Equal(t, "must be of type string", err.Message)
+}
+
+func Test_DecodeJson_Should_Reject_Trailing_Data(t *testing.T) {
+	var request cfuu.UpdateUserRequest
+	err := cfc.DecodeJson([]byte(`{"firstName":"John"} {"firstName":"Jane"}`), cfc.DecodeOptions{}, &request)
+
+	require.NotNil(t, err)
+	require.Equal(t, "trailing_data", err.Code)
+}
+
+func Test_ReadRequestBody_Should_Decode_Base64_Bodies(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		Body:            base64.StdEncoding.EncodeToString([]byte(` {"firstName":"John"} `)),
+		IsBase64Encoded: true,
+	}
+
+	body, err := cfc.ReadRequestBody(apiRequest, cfc.StrictDecodeOptions())
+
+	require.Nil(t, err)
+	require.Equal(t, `{"firstName":"John"}`, string(body))
+
+	apiRequest.Body = "not base64!"
+	_, err = cfc.ReadRequestBody(apiRequest, cfc.StrictDecodeOptions())
+
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusBadRequest, err.ErrorStatus)
+}
+
+func Test_ReadRequestBody_Should_Enforce_Max_Body_Size(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		Body: `{"biography":"` + strings.Repeat("a", cfc.DefaultMaxBodyBytes) + `"}`,
+	}
+
+	_, err := cfc.ReadRequestBody(apiRequest, cfc.StrictDecodeOptions())
+
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, err.ErrorStatus)
+	require.Equal(t, "PAYLOAD_TOO_LARGE", err.ErrorCode)
+
+	_, err = cfc.ReadRequestBody(apiRequest, cfc.DecodeOptions{})
+
+	require.Nil(t, err)
+}
@@ -0,0 +1,54 @@
+package unittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsDryRun_Should_Accept_Prefer_Header_And_Query_Parameter(t *testing.T) {
+	dryRuns := []events.APIGatewayProxyRequest{
+		{Headers: map[string]string{"Prefer": "dry-run"}},
+		{Headers: map[string]string{"prefer": "return=minimal, dry-run"}},
+		{QueryStringParameters: map[string]string{"dryRun": "true"}},
+		{QueryStringParameters: map[string]string{"dryRun": "1"}},
+	}
+	for _, apiRequest := range dryRuns {
+		require.True(t, cfc.IsDryRun(apiRequest), apiRequest)
+	}
+
+	writes := []events.APIGatewayProxyRequest{
+		{},
+		{Headers: map[string]string{"Prefer": "return=minimal"}},
+		{QueryStringParameters: map[string]string{"dryRun": "false"}},
+		{QueryStringParameters: map[string]string{"dryRun": "yes please"}},
+	}
+	for _, apiRequest := range writes {
+		require.False(t, cfc.IsDryRun(apiRequest), apiRequest)
+	}
+}
+
+func Test_DryRunMiddleware_Should_Skip_Idempotency_And_Apply_Preference(t *testing.T) {
+	dryRun := false
+	next := func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		dryRun = cfc.DryRunFromContext(ctx)
+		return events.APIGatewayProxyResponse{StatusCode: 204}
+	}
+
+	// A nil store would fail if the idempotency key were claimed
+	handler := cfc.DryRunMiddleware()(cfc.IdempotencyMiddleware(nil, time.Hour)(next))
+
+	response := handler(context.TODO(), events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Headers:    map[string]string{"Prefer": "dry-run", "Idempotency-Key": "key-123"},
+	})
+
+	require.True(t, dryRun)
+	require.Equal(t, 204, response.StatusCode)
+	require.Equal(t, "dry-run", response.Headers["Preference-Applied"])
+}
@@ -0,0 +1,74 @@
+package unittest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ErrorResponse_Should_Report_Unhandled_Errors_Without_Leaking_Details(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	request := map[string]interface{}{"Path": "/v1/users/123"}
+
+	ctx := cfc.ContextWithCorrelationId(context.TODO(), "correlation-123")
+	ctx = cfc.ContextWithErrorReporter(ctx, cfc.CreateFileErrorReporter(path), request)
+
+	response := cfc.ErrorResponse(ctx, cfe.ErrorUnhandled("unable to fetch user: secret table name"))
+
+	require.Equal(t, 500, response.StatusCode)
+	require.NotContains(t, response.Body, "secret table name")
+
+	var body cfe.ResponseError
+	require.Nil(t, json.Unmarshal([]byte(response.Body), &body))
+	require.NotEmpty(t, body.ErrorId)
+	require.Equal(t, "correlation-123", body.CorrelationId)
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	var report cfc.ErrorReport
+	require.Nil(t, json.Unmarshal([]byte(strings.TrimSpace(string(data))), &report))
+	require.Equal(t, body.ErrorId, report.ErrorId)
+	require.Equal(t, cfc.ErrorKindUnhandled, report.Kind)
+	require.Contains(t, report.Message, "secret table name")
+	require.Equal(t, "correlation-123", report.CorrelationId)
+	require.Equal(t, "/v1/users/123", report.Request["Path"])
+}
+
+func Test_ReportError_Should_Include_Panic_Stack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	ctx := cfc.ContextWithErrorReporter(context.TODO(), cfc.CreateFileErrorReporter(path), nil)
+
+	respError := cfc.ReportError(ctx, cfc.ErrorKindPanic, "index out of range", "goroutine 1 [running]:")
+	response := cfc.ErrorResponse(ctx, respError)
+
+	require.Equal(t, 500, response.StatusCode)
+	require.NotContains(t, response.Body, "index out of range")
+	require.Contains(t, response.Body, respError.ErrorId)
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	// The error is only reported once, when it is created
+	require.Equal(t, 1, strings.Count(string(data), "\n"))
+	require.Contains(t, string(data), "goroutine 1 [running]:")
+}
+
+func Test_ErrorResponse_Should_Not_Report_Client_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	ctx := cfc.ContextWithErrorReporter(context.TODO(), cfc.CreateFileErrorReporter(path), nil)
+
+	response := cfc.ErrorResponse(ctx, cfe.ErrorNotFound())
+
+	require.Equal(t, 404, response.StatusCode)
+	require.NotContains(t, response.Body, "errorId")
+	require.NoFileExists(t, path)
+}
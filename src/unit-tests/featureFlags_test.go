@@ -0,0 +1,103 @@
+package unittest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFlagParameterClient struct {
+	value *string
+	err   error
+	calls int
+}
+
+func (client *fakeFlagParameterClient) GetParameter(ctx context.Context, input *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	client.calls++
+	if client.err != nil {
+		return nil, client.err
+	}
+
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Name: input.Name, Value: client.value}}, nil
+}
+
+func Test_FeatureFlags_Should_Evaluate_Flags_From_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	err := os.WriteFile(path, []byte(`{"on": {"enabled": true}, "off": {"enabled": false}, "none": {"enabled": true, "percentage": 0}}`), 0600)
+	require.Nil(t, err)
+
+	flags := cfc.CreateFeatureFlags(cfc.FileFlagProvider{Path: path})
+	ctx := cfc.ContextWithRequesterOid(context.TODO(), "01J0000000000000000000000")
+
+	require.True(t, flags.IsEnabled(ctx, "on", false))
+	require.False(t, flags.IsEnabled(ctx, "off", true))
+	require.False(t, flags.IsEnabled(ctx, "none", true))
+	require.True(t, flags.IsEnabled(ctx, "unknown", true))
+	require.False(t, flags.IsEnabled(ctx, "unknown", false))
+}
+
+func Test_FeatureFlags_Should_Return_Default_Without_Provider(t *testing.T) {
+	var missing *cfc.FeatureFlags
+	require.True(t, missing.IsEnabled(context.TODO(), "on", true))
+
+	flags := cfc.CreateFeatureFlags(cfc.FileFlagProvider{Path: filepath.Join(t.TempDir(), "missing.json")})
+	require.True(t, flags.IsEnabled(context.TODO(), "on", true))
+}
+
+func Test_FeatureFlags_Should_Roll_Out_By_Requester(t *testing.T) {
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		requesterOid := fmt.Sprintf("requester-%v", i)
+		inRollout := cfc.IsInRollout("new-validation", requesterOid, 25)
+
+		// A requester stays in the rollout as it grows
+		require.Equal(t, inRollout, cfc.IsInRollout("new-validation", requesterOid, 25))
+		if inRollout {
+			require.True(t, cfc.IsInRollout("new-validation", requesterOid, 50))
+			enabled++
+		}
+	}
+
+	require.InDelta(t, 250, enabled, 50)
+	require.False(t, cfc.IsInRollout("new-validation", "", 99))
+	require.True(t, cfc.IsInRollout("new-validation", "", 100))
+}
+
+func Test_FeatureFlags_Should_Cache_Parameter_For_Ttl(t *testing.T) {
+	client := &fakeFlagParameterClient{value: aws.String(`{"on": {"enabled": true}}`)}
+	provider := cfc.CreateSsmFlagProvider(client, "/cf-user/dev/feature-flags", time.Hour)
+	flags := cfc.CreateFeatureFlags(provider)
+
+	require.True(t, flags.IsEnabled(context.TODO(), "on", false))
+	require.True(t, flags.IsEnabled(context.TODO(), "on", false))
+	require.Equal(t, 1, client.calls)
+
+	expired := cfc.CreateSsmFlagProvider(client, "/cf-user/dev/feature-flags", 0)
+	_, err := expired.Flags(context.TODO())
+	require.Nil(t, err)
+
+	// Stale flags are kept when a refresh fails
+	client.err = errors.New("throttled")
+	current, err := expired.Flags(context.TODO())
+	require.Nil(t, err)
+	require.True(t, current["on"].Enabled)
+}
+
+func Test_FeatureFlags_Should_Treat_Missing_Parameter_As_No_Flags(t *testing.T) {
+	client := &fakeFlagParameterClient{err: &types.ParameterNotFound{}}
+	flags := cfc.CreateFeatureFlags(cfc.CreateSsmFlagProvider(client, "/cf-user/dev/feature-flags", time.Minute))
+
+	require.True(t, flags.IsEnabled(context.TODO(), "on", true))
+	require.False(t, flags.IsEnabled(context.TODO(), "on", false))
+}
@@ -0,0 +1,104 @@
+package unittest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHealthProbe struct {
+	statuses   map[string]string
+	indexError error
+}
+
+func (probe fakeHealthProbe) TableName() string {
+	return "cf-user-test-app-user"
+}
+
+func (probe fakeHealthProbe) IndexNames() []string {
+	return []string{"GSI1", "GSI2", "GSI3"}
+}
+
+func (probe fakeHealthProbe) DescribeUserTable(ctx context.Context) (map[string]string, error) {
+	return probe.statuses, nil
+}
+
+func (probe fakeHealthProbe) ProbeUserTable(ctx context.Context) error {
+	return nil
+}
+
+func (probe fakeHealthProbe) ProbeUserIndex(ctx context.Context, indexName string) error {
+	if indexName == "GSI3" {
+		return probe.indexError
+	}
+
+	return nil
+}
+
+func activeStatuses() map[string]string {
+	return map[string]string{"cf-user-test-app-user": "ACTIVE", "GSI1": "ACTIVE", "GSI2": "ACTIVE", "GSI3": "ACTIVE"}
+}
+
+func healthHandler(status cfe.HealthStatus) cfc.RequestHandlerFunc {
+	next := func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		response := cfc.CreateHealthResponse("cf-user", "test", false)
+		response.Status = status.String()
+
+		body, _ := json.Marshal(response)
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: string(body)}
+	}
+
+	return cfc.HealthMiddleware()(next)
+}
+
+func Test_CheckUserTable_Should_Pass_When_Table_And_Indexes_Respond(t *testing.T) {
+	checks, status := cfc.CheckUserTable(context.TODO(), fakeHealthProbe{statuses: activeStatuses()})
+
+	require.Equal(t, cfe.HealthPass, status)
+	require.Len(t, checks["dynamodb:describeTable"], 4)
+	require.Len(t, checks["dynamodb:responseTime"], 4)
+	require.Equal(t, "cf-user-test-app-user", checks["dynamodb:responseTime"][0].ComponentId)
+	require.Equal(t, "ms", checks["dynamodb:responseTime"][0].ObservedUnit)
+}
+
+func Test_CheckUserTable_Should_Report_Worst_Status(t *testing.T) {
+	statuses := activeStatuses()
+	statuses["GSI2"] = "UPDATING"
+
+	_, status := cfc.CheckUserTable(context.TODO(), fakeHealthProbe{statuses: statuses})
+	require.Equal(t, cfe.HealthWarn, status)
+
+	delete(statuses, "GSI1")
+	checks, status := cfc.CheckUserTable(context.TODO(), fakeHealthProbe{statuses: statuses, indexError: errors.New("unable to probe user index GSI3")})
+	require.Equal(t, cfe.HealthFail, status)
+
+	var failed []string
+	for _, check := range append(checks["dynamodb:describeTable"], checks["dynamodb:responseTime"]...) {
+		if check.Status == "fail" {
+			failed = append(failed, check.ComponentId+": "+check.Output)
+		}
+	}
+	require.Equal(t, []string{"GSI1: not found", "GSI3: unreachable"}, failed)
+}
+
+func Test_HealthMiddleware_Should_Return_503_When_Failing(t *testing.T) {
+	response := healthHandler(cfe.HealthWarn)(context.TODO(), events.APIGatewayProxyRequest{})
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, cfc.MediaTypeHealthJson, response.Headers["Content-Type"])
+
+	response = healthHandler(cfe.HealthFail)(context.TODO(), events.APIGatewayProxyRequest{})
+	require.Equal(t, 503, response.StatusCode)
+
+	var health cfm.HealthResponse
+	require.Nil(t, json.Unmarshal([]byte(response.Body), &health))
+	require.Equal(t, "fail", health.Status)
+	require.Equal(t, cfc.BuildVersion, health.ReleaseId)
+}
@@ -0,0 +1,95 @@
+package unittest
+
+import (
+	"log/slog"
+	"testing"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+func debugRequest(permissions string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		Headers: map[string]string{"x-debug-log": "true"},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{"permissions": permissions},
+		},
+	}
+}
+
+func Test_ResolveLogLevel_Should_Allow_Debug_Header_For_Admins(t *testing.T) {
+	level, reason := cfc.ResolveLogLevel(debugRequest("cf:admin:user"), slog.LevelInfo, 0)
+	require.Equal(t, slog.LevelDebug, level)
+	require.Equal(t, cfc.DebugReasonHeader, reason)
+
+	level, reason = cfc.ResolveLogLevel(debugRequest("cf:read:user cf:update:user"), slog.LevelInfo, 0)
+	require.Equal(t, slog.LevelInfo, level)
+	require.Empty(t, reason)
+}
+
+func Test_ResolveLogLevel_Should_Sample_Debug_Requests(t *testing.T) {
+	level, reason := cfc.ResolveLogLevel(events.APIGatewayProxyRequest{}, slog.LevelWarn, 1)
+	require.Equal(t, slog.LevelDebug, level)
+	require.Equal(t, cfc.DebugReasonSampled, reason)
+
+	level, _ = cfc.ResolveLogLevel(events.APIGatewayProxyRequest{}, slog.LevelWarn, 0)
+	require.Equal(t, slog.LevelWarn, level)
+
+	level, reason = cfc.ResolveLogLevel(events.APIGatewayProxyRequest{}, slog.LevelDebug, 0)
+	require.Equal(t, slog.LevelDebug, level)
+	require.Empty(t, reason)
+}
+
+func Test_SanitizeDynamoDbValue_Should_Mask_Attribute_Values(t *testing.T) {
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("cf-user-dev-app-user"),
+		Item: map[string]types.AttributeValue{
+			"UserId":       &types.AttributeValueMemberS{Value: "01J0000000000000000000000"},
+			"EmailAddress": &types.AttributeValueMemberS{Value: "jane.doe@example.com"},
+			"PrimaryAddress": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"Line1": &types.AttributeValueMemberS{Value: "1 Main Street"},
+			}},
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: "EMAIL#jane.doe@example.com"},
+		},
+	}
+
+	standard := cfc.SanitizeDynamoDbValue(cfc.CreateRedactor(cfe.RedactionPolicyStandard), input).(map[string]interface{})
+	item := standard["Item"].(map[string]interface{})
+
+	require.Equal(t, "cf-user-dev-app-user", standard["TableName"])
+	require.Equal(t, "01J0000000000000000000000", item["UserId"])
+	require.Equal(t, "j*******@example.com", item["EmailAddress"])
+	require.Equal(t, "*********reet", item["PrimaryAddress"].(map[string]interface{})["Line1"])
+	require.NotContains(t, standard["ExpressionAttributeValues"].(map[string]interface{})[":pk"], "jane")
+
+	strict := cfc.SanitizeDynamoDbValue(cfc.CreateRedactor(cfe.RedactionPolicyStrict), input).(map[string]interface{})
+	require.Equal(t, "[REDACTED]", strict["Item"].(map[string]interface{})["EmailAddress"])
+}
+
+func Test_SanitizeDynamoDbValue_Should_Keep_Initials_And_Redact_Addresses(t *testing.T) {
+	input := &dynamodb.PutItemInput{
+		Item: map[string]types.AttributeValue{
+			"FirstName": &types.AttributeValueMemberS{Value: "Johnathan"},
+			"BillingAddress": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"AddressOne": &types.AttributeValueMemberS{Value: "123 Sunshine Street"},
+				"PostalCode": &types.AttributeValueMemberS{Value: "84103"},
+			}},
+		},
+	}
+
+	standard := cfc.SanitizeDynamoDbValue(cfc.CreateRedactor(cfe.RedactionPolicyStandard), input).(map[string]interface{})
+	item := standard["Item"].(map[string]interface{})
+	address := item["BillingAddress"].(map[string]interface{})
+
+	require.Equal(t, "J***", item["FirstName"])
+	require.Equal(t, "[REDACTED]", address["AddressOne"])
+	require.Equal(t, "[REDACTED]", address["PostalCode"])
+}
@@ -0,0 +1,73 @@
+package unittest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func maintenanceHandler(role cfe.LambdaRole, readOnly bool, flags *cfc.FeatureFlags) cfc.RequestHandlerFunc {
+	next := func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{StatusCode: 204}
+	}
+
+	return cfc.MaintenanceMiddleware(role, readOnly, 5*time.Minute, flags)(next)
+}
+
+func Test_Maintenance_Should_Reject_Writes_When_Read_Only(t *testing.T) {
+	for _, role := range []cfe.LambdaRole{cfe.CreateUser, cfe.UpdateUser, cfe.DeleteUser} {
+		response := maintenanceHandler(role, true, nil)(context.TODO(), events.APIGatewayProxyRequest{})
+
+		require.Equal(t, 503, response.StatusCode)
+		require.Equal(t, "300", response.Headers["Retry-After"])
+
+		var respError cfe.ResponseError
+		require.Nil(t, json.Unmarshal([]byte(response.Body), &respError))
+		require.Equal(t, "MAINTENANCE", respError.ErrorCode)
+	}
+}
+
+func Test_Maintenance_Should_Serve_Reads_When_Read_Only(t *testing.T) {
+	response := maintenanceHandler(cfe.ReadUser, true, nil)(context.TODO(), events.APIGatewayProxyRequest{})
+	require.Equal(t, 204, response.StatusCode)
+
+	response = maintenanceHandler(cfe.CreateUser, false, nil)(context.TODO(), events.APIGatewayProxyRequest{})
+	require.Equal(t, 204, response.StatusCode)
+}
+
+func Test_Maintenance_Should_Allow_Break_Glass_Permission(t *testing.T) {
+	apiRequest := events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{"permissions": "cf:update:user cf:bypass:maintenance"},
+		},
+	}
+
+	response := maintenanceHandler(cfe.UpdateUser, true, nil)(context.TODO(), apiRequest)
+	require.Equal(t, 204, response.StatusCode)
+
+	for _, permissions := range []string{"cf:*:user", "cf:*:*", "cf:superadmin", "cf:bypass:*"} {
+		apiRequest.RequestContext.Authorizer["permissions"] = permissions
+		response = maintenanceHandler(cfe.UpdateUser, true, nil)(context.TODO(), apiRequest)
+		require.Equal(t, 503, response.StatusCode, permissions)
+	}
+}
+
+func Test_Maintenance_Should_Follow_Read_Only_Flag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	err := os.WriteFile(path, []byte(`{"read-only": {"enabled": true}}`), 0600)
+	require.Nil(t, err)
+
+	flags := cfc.CreateFeatureFlags(cfc.FileFlagProvider{Path: path})
+
+	response := maintenanceHandler(cfe.DeleteUser, false, flags)(context.TODO(), events.APIGatewayProxyRequest{})
+	require.Equal(t, 503, response.StatusCode)
+}
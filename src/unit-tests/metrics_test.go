@@ -0,0 +1,95 @@
+package unittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseMetricRecords(t *testing.T, output string) []map[string]interface{} {
+	records := make([]map[string]interface{}, 0)
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var record map[string]interface{}
+		require.Nil(t, json.Unmarshal([]byte(line), &record))
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func Test_Metrics_Should_Write_Embedded_Metric_Format_Records(t *testing.T) {
+	var output bytes.Buffer
+
+	metrics := cfc.CreateMetrics("cf-user", &output,
+		cfc.MetricDimension{Name: "Service", Value: "cf-user"},
+		cfc.MetricDimension{Name: "Stage", Value: "test"},
+	)
+	metrics.SetProperty("FunctionRequestId", "request-id")
+	metrics.IncrementCounter("ColdStart")
+	metrics.AddMetric("HandlerLatency", cfe.MetricUnitMilliseconds, 12.5)
+	metrics.AddMetric("HandlerLatency", cfe.MetricUnitMilliseconds, 7)
+
+	require.Nil(t, metrics.Flush())
+
+	records := parseMetricRecords(t, output.String())
+	require.Equal(t, 1, len(records))
+
+	record := records[0]
+	require.Equal(t, "cf-user", record["Service"])
+	require.Equal(t, "test", record["Stage"])
+	require.Equal(t, "request-id", record["FunctionRequestId"])
+	require.Equal(t, 1.0, record["ColdStart"])
+	require.Equal(t, []interface{}{12.5, 7.0}, record["HandlerLatency"])
+
+	aws := record["_aws"].(map[string]interface{})
+	definition := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+
+	require.Equal(t, "cf-user", definition["Namespace"])
+	require.Equal(t, []interface{}{[]interface{}{"Service", "Stage"}}, definition["Dimensions"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"Name": "ColdStart", "Unit": "Count"},
+		map[string]interface{}{"Name": "HandlerLatency", "Unit": "Milliseconds"},
+	}, definition["Metrics"])
+}
+
+func Test_Metrics_Should_Write_Separate_Records_Per_Dimension_Set(t *testing.T) {
+	var output bytes.Buffer
+
+	metrics := cfc.CreateMetrics("cf-user", &output, cfc.MetricDimension{Name: "Service", Value: "cf-user"})
+	metrics.IncrementCounter("UsersCreated", cfc.MetricDimension{Name: "AccountType", Value: cfe.BusinessAccount.String()})
+	metrics.IncrementCounter("UsersCreated", cfc.MetricDimension{Name: "AccountType", Value: cfe.PersonalAccount.String()})
+
+	require.Nil(t, metrics.Flush())
+
+	records := parseMetricRecords(t, output.String())
+	require.Equal(t, 2, len(records))
+
+	for _, record := range records {
+		definition := record["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+
+		require.Equal(t, []interface{}{[]interface{}{"Service", "AccountType"}}, definition["Dimensions"])
+		require.Equal(t, 1.0, record["UsersCreated"])
+	}
+
+	require.Equal(t, "Business", records[0]["AccountType"])
+	require.Equal(t, "Personal", records[1]["AccountType"])
+
+	output.Reset()
+	require.Nil(t, metrics.Flush())
+	require.Equal(t, "", output.String())
+}
+
+func Test_Metrics_Should_Ignore_Values_Without_Collector(t *testing.T) {
+	var metrics *cfc.Metrics
+
+	metrics.IncrementCounter("ColdStart")
+	require.Nil(t, metrics.Flush())
+}
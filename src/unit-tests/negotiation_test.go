@@ -0,0 +1,113 @@
+package unittest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func whenWeNegotiate(headers map[string]string, body string) events.APIGatewayProxyResponse {
+	next := func(ctx context.Context, apiRequest events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       body,
+			Headers:    map[string]string{cfc.ETagHeader: `"abc"`},
+		}
+	}
+
+	return cfc.ContentNegotiationMiddleware(cfc.DefaultCompressionMinBytes)(next)(context.TODO(), events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Headers:    headers,
+	})
+}
+
+func Test_Negotiation_Should_Default_To_Uncompressed_Json(t *testing.T) {
+	body := `{"userId":"01J0000000000000000000000"}`
+
+	response := whenWeNegotiate(nil, body)
+
+	require.Equal(t, body, response.Body)
+	require.False(t, response.IsBase64Encoded)
+	require.Equal(t, "application/json", response.Headers["Content-Type"])
+	require.Equal(t, "Accept, Accept-Encoding", response.Headers["Vary"])
+	require.NotContains(t, response.Headers, "Content-Encoding")
+	require.Equal(t, `"abc"`, response.Headers["ETag"])
+}
+
+func Test_Negotiation_Should_Gzip_Large_Bodies(t *testing.T) {
+	body := `{"biography":"` + strings.Repeat("a", 2048) + `"}`
+
+	response := whenWeNegotiate(map[string]string{"accept-encoding": "gzip, deflate"}, body)
+
+	require.True(t, response.IsBase64Encoded)
+	require.Equal(t, "gzip", response.Headers["Content-Encoding"])
+	require.Equal(t, `W/"abc"`, response.Headers["ETag"])
+
+	compressed, err := base64.StdEncoding.DecodeString(response.Body)
+	require.Nil(t, err)
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.Nil(t, err)
+	decompressed, err := io.ReadAll(reader)
+	require.Nil(t, err)
+
+	require.Equal(t, body, string(decompressed))
+}
+
+func Test_Negotiation_Should_Not_Compress_Small_Bodies(t *testing.T) {
+	response := whenWeNegotiate(map[string]string{"Accept-Encoding": "gzip"}, `{"userId":"1"}`)
+
+	require.False(t, response.IsBase64Encoded)
+	require.NotContains(t, response.Headers, "Content-Encoding")
+}
+
+func Test_Negotiation_Should_Encode_Cbor_And_Msgpack(t *testing.T) {
+	body := `{"userId":"01J0000000000000000000000","count":3}`
+
+	response := whenWeNegotiate(map[string]string{"Accept": "application/cbor"}, body)
+	require.Equal(t, "application/cbor", response.Headers["Content-Type"])
+	require.Equal(t, `W/"abc"`, response.Headers["ETag"])
+	require.True(t, response.IsBase64Encoded)
+
+	data, err := base64.StdEncoding.DecodeString(response.Body)
+	require.Nil(t, err)
+	var cborDocument map[string]interface{}
+	require.Nil(t, cbor.Unmarshal(data, &cborDocument))
+	require.Equal(t, "01J0000000000000000000000", cborDocument["userId"])
+	require.Equal(t, uint64(3), cborDocument["count"])
+
+	response = whenWeNegotiate(map[string]string{"Accept": "application/x-msgpack"}, body)
+	require.Equal(t, "application/msgpack", response.Headers["Content-Type"])
+	require.Equal(t, `W/"abc"`, response.Headers["ETag"])
+
+	data, err = base64.StdEncoding.DecodeString(response.Body)
+	require.Nil(t, err)
+	var msgpackDocument map[string]interface{}
+	require.Nil(t, msgpack.Unmarshal(data, &msgpackDocument))
+	require.Equal(t, "01J0000000000000000000000", msgpackDocument["userId"])
+	require.EqualValues(t, 3, msgpackDocument["count"])
+}
+
+func Test_Negotiation_Should_Respect_Weights(t *testing.T) {
+	require.Equal(t, "application/json", cfc.NegotiateMediaType(""))
+	require.Equal(t, "application/json", cfc.NegotiateMediaType("*/*"))
+	require.Equal(t, "application/json", cfc.NegotiateMediaType("text/html"))
+	require.Equal(t, "application/cbor", cfc.NegotiateMediaType("application/json;q=0.5, application/cbor"))
+	require.Equal(t, "application/msgpack", cfc.NegotiateMediaType("application/msgpack, application/*;q=0.1"))
+
+	require.Equal(t, "", cfc.NegotiateContentCoding(""))
+	require.Equal(t, "", cfc.NegotiateContentCoding("identity, deflate"))
+	require.Equal(t, "br", cfc.NegotiateContentCoding("gzip, br"))
+	require.Equal(t, "gzip", cfc.NegotiateContentCoding("gzip, br;q=0.5"))
+	require.Equal(t, "gzip", cfc.NegotiateContentCoding("br;q=0, *"))
+}
@@ -0,0 +1,80 @@
+package unittest
+
+import (
+	"fmt"
+	"testing"
+
+	cfe "cf-user/core/enums"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Permissions_Should_Match_Permission_Matrix(t *testing.T) {
+	matrix := []struct {
+		granted  string
+		required cfe.LambdaRole
+		allowed  bool
+	}{
+		{"cf:read:user", cfe.ReadUser, true},
+		{"cf:read:user", cfe.UpdateUser, false},
+		{"cf:update:user", cfe.UpdateUser, true},
+		{"cf:*:user", cfe.CreateUser, true},
+		{"cf:*:user", cfe.DeleteUser, true},
+		{"cf:read:*", cfe.ReadUser, true},
+		{"cf:read:*", cfe.UpdateUser, false},
+		{"cf:*:*", cfe.DeleteUser, true},
+		{"cf:*:group", cfe.ReadUser, false},
+		{"cf:*", cfe.ReadUser, false},
+		{"cf:read:user:extra", cfe.ReadUser, false},
+		{"cf:superadmin", cfe.DeleteUser, true},
+		{"", cfe.ReadUser, false},
+	}
+
+	for _, row := range matrix {
+		permissions := cfe.Permissions{row.granted}
+		require.Equal(t, row.allowed, row.required.IsSatisfiedBy(permissions), fmt.Sprintf("%v granting %v", row.granted, row.required))
+	}
+}
+
+func Test_GetPermissions_Should_Accept_Claim_Shapes(t *testing.T) {
+	expected := cfe.Permissions{"cf:read:user", "cf:update:user"}
+
+	shapes := []interface{}{
+		"cf:read:user,cf:update:user",
+		"cf:read:user, cf:update:user",
+		"cf:read:user cf:update:user",
+		`["cf:read:user","cf:update:user"]`,
+		[]interface{}{"cf:read:user", "cf:update:user"},
+		[]string{"cf:read:user", "cf:update:user"},
+	}
+
+	for _, shape := range shapes {
+		permissions := cfe.GetPermissions(map[string]interface{}{"permissions": shape})
+		require.Equal(t, expected, permissions, fmt.Sprintf("%#v", shape))
+	}
+
+	require.Empty(t, cfe.GetPermissions(map[string]interface{}{}))
+	require.Empty(t, cfe.GetPermissions(map[string]interface{}{"permissions": 42}))
+}
+
+func Test_LambdaRole_Should_Not_Panic_For_Array_Claims(t *testing.T) {
+	authContext := map[string]interface{}{
+		"permissions": []interface{}{"cf:read:user", 7},
+	}
+
+	require.True(t, cfe.ReadUser.ExistsInAuthContext(authContext))
+	require.False(t, cfe.DeleteUser.ExistsInAuthContext(authContext))
+}
+
+func Test_AuthRequirement_Should_Combine_Roles(t *testing.T) {
+	permissions := cfe.Permissions{"cf:read:user", "cf:update:user"}
+
+	require.True(t, cfe.AnyOf(cfe.DeleteUser, cfe.ReadUser).IsSatisfiedBy(permissions))
+	require.False(t, cfe.AnyOf(cfe.DeleteUser, cfe.CreateUser).IsSatisfiedBy(permissions))
+	require.True(t, cfe.AllOf(cfe.ReadUser, cfe.UpdateUser).IsSatisfiedBy(permissions))
+	require.False(t, cfe.AllOf(cfe.ReadUser, cfe.DeleteUser).IsSatisfiedBy(permissions))
+	require.True(t, cfe.AllOf(cfe.ReadUser, cfe.AnyOf(cfe.DeleteUser, cfe.UpdateUser)).IsSatisfiedBy(permissions))
+	require.False(t, cfe.AllOf().IsSatisfiedBy(permissions))
+
+	require.Equal(t, "allOf(cf:read:user,anyOf(cf:delete:user,cf:update:user))", cfe.AllOf(cfe.ReadUser, cfe.AnyOf(cfe.DeleteUser, cfe.UpdateUser)).String())
+}
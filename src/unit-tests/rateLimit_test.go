@@ -0,0 +1,86 @@
+package unittest
+
+import (
+	"testing"
+	"time"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RateLimit_Should_Start_With_Full_Bucket(t *testing.T) {
+	limit := cfc.RateLimit{Capacity: 5, RefillPerSecond: 1}
+	bucket := &cfm.RateLimitBucket{}
+	now := time.Now()
+
+	decision := limit.Take(bucket, now)
+
+	require.True(t, decision.Allowed)
+	require.Equal(t, 4, decision.Remaining)
+	require.Equal(t, time.Second, decision.Reset)
+	require.Equal(t, float64(4), bucket.Tokens)
+	require.Equal(t, now.UnixMilli(), bucket.UpdatedAt)
+	require.Greater(t, bucket.ExpiresAt, now.Unix())
+}
+
+func Test_RateLimit_Should_Reject_When_Bucket_Is_Empty(t *testing.T) {
+	limit := cfc.RateLimit{Capacity: 2, RefillPerSecond: 0.5}
+	bucket := &cfm.RateLimitBucket{}
+	now := time.Now()
+
+	require.True(t, limit.Take(bucket, now).Allowed)
+	require.True(t, limit.Take(bucket, now).Allowed)
+
+	decision := limit.Take(bucket, now)
+
+	require.False(t, decision.Allowed)
+	require.Equal(t, 0, decision.Remaining)
+	require.Equal(t, 2*time.Second, decision.RetryAfter)
+	require.Equal(t, 4*time.Second, decision.Reset)
+}
+
+func Test_RateLimit_Should_Refill_Over_Time(t *testing.T) {
+	limit := cfc.RateLimit{Capacity: 10, RefillPerSecond: 2}
+	now := time.Now()
+	bucket := &cfm.RateLimitBucket{Tokens: 0, UpdatedAt: now.Add(-1500 * time.Millisecond).UnixMilli()}
+
+	decision := limit.Take(bucket, now)
+
+	require.True(t, decision.Allowed)
+	require.Equal(t, 2, decision.Remaining)
+}
+
+func Test_RateLimit_Should_Not_Refill_Beyond_Capacity(t *testing.T) {
+	limit := cfc.RateLimit{Capacity: 3, RefillPerSecond: 1}
+	now := time.Now()
+	bucket := &cfm.RateLimitBucket{Tokens: 1, UpdatedAt: now.Add(-time.Hour).UnixMilli()}
+
+	decision := limit.Take(bucket, now)
+
+	require.True(t, decision.Allowed)
+	require.Equal(t, 2, decision.Remaining)
+}
+
+func Test_GetRateLimit_Should_Apply_Overrides_By_Role(t *testing.T) {
+	limit, err := cfc.GetRateLimit(cfe.ReadUser, `{"cf:read:user":{"capacity":50,"refillPerSecond":5}}`)
+
+	require.Nil(t, err)
+	require.Equal(t, float64(50), limit.Capacity)
+	require.Equal(t, float64(5), limit.RefillPerSecond)
+
+	limit, err = cfc.GetRateLimit(cfe.CreateUser, `{"cf:read:user":{"capacity":50,"refillPerSecond":5}}`)
+
+	require.Nil(t, err)
+	require.Equal(t, float64(10), limit.Capacity)
+}
+
+func Test_GetRateLimit_Should_Fail_For_Invalid_Limits(t *testing.T) {
+	_, err := cfc.GetRateLimit(cfe.ReadUser, `{"cf:read:user":{"capacity":10,"refillPerSecond":0}}`)
+	require.NotNil(t, err)
+
+	_, err = cfc.GetRateLimit(cfe.ReadUser, `not json`)
+	require.NotNil(t, err)
+}
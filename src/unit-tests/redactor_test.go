@@ -0,0 +1,119 @@
+package unittest
+
+import (
+	"testing"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+	cfcu "cf-user/create-user"
+
+	"github.com/stretchr/testify/require"
+)
+
+func givenSensitiveCreateUserRequest() *cfcu.CreateUserRequest {
+	username := "jdoe"
+	firstName := "Johnathan"
+	phoneNumber := "8011239088"
+	addressOne := "123 Sunshine Street"
+	city := "Salt Lake City"
+	postalCode := "84103"
+
+	return &cfcu.CreateUserRequest{
+		Username:     &username,
+		FirstName:    &firstName,
+		PhoneNumber:  &phoneNumber,
+		EmailAddress: "john.doe@canary-classifind.com",
+		PrimaryAddress: &cfm.Address{
+			AddressOne: &addressOne,
+			City:       &city,
+			PostalCode: &postalCode,
+		},
+	}
+}
+
+func Test_Redactor_Should_Mask_And_Redact_Tagged_Fields_With_Standard_Policy(t *testing.T) {
+	redactor := cfc.CreateRedactor(cfe.RedactionPolicyStandard)
+
+	sanitized := redactor.Sanitize(givenSensitiveCreateUserRequest()).(map[string]interface{})
+
+	require.Equal(t, "jdoe", sanitized["username"])
+	require.Equal(t, "J***", sanitized["firstName"])
+	require.Equal(t, "******9088", sanitized["phoneNumber"])
+	require.Equal(t, "j*******@canary-classifind.com", sanitized["emailAddress"])
+	require.Nil(t, sanitized["lastName"])
+
+	address := sanitized["primaryAddress"].(map[string]interface{})
+	require.Equal(t, "[REDACTED]", address["addressOne"])
+	require.Nil(t, address["addressTwo"])
+	require.Equal(t, "Salt Lake City", address["city"])
+	require.Equal(t, "[REDACTED]", address["postalCode"])
+}
+
+func Test_Redactor_Should_Keep_Only_Initials_And_Redact_Addresses_With_Standard_Policy(t *testing.T) {
+	redactor := cfc.CreateRedactor(cfe.RedactionPolicyStandard)
+
+	firstName := "Jo"
+	lastName := "Doe-Smithson"
+	addressOne := "123 Sunshine Street"
+	addressTwo := "Unit 69"
+	postalCode := "841031234"
+	user := &cfm.User{
+		FirstName: &firstName,
+		LastName:  &lastName,
+		BillingAddress: &cfm.Address{
+			AddressOne: &addressOne,
+			AddressTwo: &addressTwo,
+			PostalCode: &postalCode,
+		},
+	}
+
+	sanitized := redactor.Sanitize(user).(map[string]interface{})
+
+	require.Equal(t, "J***", sanitized["firstName"])
+	require.Equal(t, "D***", sanitized["lastName"])
+
+	address := sanitized["billingAddress"].(map[string]interface{})
+	require.Equal(t, "[REDACTED]", address["addressOne"])
+	require.Equal(t, "[REDACTED]", address["addressTwo"])
+	require.Equal(t, "[REDACTED]", address["postalCode"])
+
+	response := redactor.Sanitize(cfm.CreateUserResponse(user, cfe.UserViewFull)).(map[string]interface{})
+	require.Equal(t, "D***", response["lastName"])
+}
+
+func Test_Redactor_Should_Redact_Masked_Fields_With_Strict_Policy(t *testing.T) {
+	redactor := cfc.CreateRedactor(cfe.RedactionPolicyStrict)
+
+	sanitized := redactor.Sanitize(givenSensitiveCreateUserRequest()).(map[string]interface{})
+
+	require.Equal(t, "jdoe", sanitized["username"])
+	require.Equal(t, "[REDACTED]", sanitized["firstName"])
+	require.Equal(t, "[REDACTED]", sanitized["phoneNumber"])
+	require.Equal(t, "[REDACTED]", sanitized["primaryAddress"].(map[string]interface{})["postalCode"])
+	require.Equal(t, "[REDACTED]", sanitized["emailAddress"])
+	require.Equal(t, "[REDACTED]", redactor.Mask("john.doe@canary-classifind.com"))
+}
+
+func Test_Redactor_Should_Leave_Values_Untouched_With_None_Policy(t *testing.T) {
+	redactor := cfc.CreateRedactor(cfe.RedactionPolicyNone)
+
+	request := givenSensitiveCreateUserRequest()
+	sanitized := redactor.Sanitize(request).(map[string]interface{})
+
+	require.Equal(t, *request.FirstName, sanitized["firstName"])
+	require.Equal(t, request.EmailAddress, sanitized["emailAddress"])
+
+	address := sanitized["primaryAddress"].(map[string]interface{})
+	require.Equal(t, *request.PrimaryAddress.AddressOne, address["addressOne"])
+}
+
+func Test_Redactor_Should_Default_To_Strict_Policy(t *testing.T) {
+	policy, err := cfe.GetRedactionPolicy(nil)
+	require.Nil(t, err)
+	require.Equal(t, cfe.RedactionPolicyStrict, *policy)
+
+	invalid := "verbose"
+	_, err = cfe.GetRedactionPolicy(&invalid)
+	require.NotNil(t, err)
+}
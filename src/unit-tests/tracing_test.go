@@ -0,0 +1,83 @@
+package unittest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	cfc "cf-user/core"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Tracing_Should_Continue_XRay_Trace_From_Request_Header(t *testing.T) {
+	t.Setenv("_X_AMZN_TRACE_ID", "")
+
+	var output bytes.Buffer
+
+	exporter, err := cfc.CreateSpanExporter(context.TODO(), cfc.TraceExporterStdout, "", &output)
+	require.Nil(t, err)
+
+	provider := cfc.CreateTracerProvider(exporter, "cf-user", "test")
+	defer provider.Shutdown(context.TODO())
+
+	apiRequest := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		},
+	}
+
+	ctx := cfc.ContextWithTraceParent(context.TODO(), apiRequest)
+
+	_, span := provider.Tracer("test").Start(ctx, "DynamoDbStore.GetUser")
+	span.End()
+
+	require.Equal(t, "5759e988bd862e3fe1be46a994272793", span.SpanContext().TraceID().String())
+	require.Contains(t, output.String(), "DynamoDbStore.GetUser")
+	require.Contains(t, output.String(), "53995c3f42cd8ad8")
+}
+
+func Test_Tracing_Should_Export_Spans_To_Otlp_Collector(t *testing.T) {
+	var mutex sync.Mutex
+	received := make([]*http.Request, 0)
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		received = append(received, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exporter, err := cfc.CreateSpanExporter(context.TODO(), cfc.TraceExporterOtlp, collector.URL+"/v1/traces", nil)
+	require.Nil(t, err)
+
+	provider := cfc.CreateTracerProvider(exporter, "cf-user", "test")
+	defer provider.Shutdown(context.TODO())
+
+	_, span := provider.Tracer("test").Start(context.TODO(), "HandleRequest")
+	span.End()
+
+	require.Nil(t, provider.ForceFlush(context.TODO()))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	require.Equal(t, 1, len(received))
+	require.Equal(t, "/v1/traces", received[0].URL.Path)
+	require.Equal(t, "application/x-protobuf", received[0].Header.Get("Content-Type"))
+}
+
+func Test_Tracing_Should_Not_Create_Exporter_When_Disabled(t *testing.T) {
+	exporter, err := cfc.CreateSpanExporter(context.TODO(), cfc.TraceExporterNone, "", nil)
+	require.Nil(t, err)
+	require.Nil(t, exporter)
+
+	_, err = cfc.CreateSpanExporter(context.TODO(), "zipkin", "", nil)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,56 @@
+package unittest
+
+import (
+	"encoding/json"
+	"testing"
+
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+	cfgu "cf-user/get-user"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UserResponse_Should_Only_Include_Selected_Fields(t *testing.T) {
+	response := cfm.CreateUserResponse(givenStoredUser(), cfe.UserViewFull)
+	response.SelectFields([]string{"username", "firstName", "profileImageId"})
+
+	body, err := json.Marshal(response)
+	require.Nil(t, err)
+
+	var fields map[string]interface{}
+	require.Nil(t, json.Unmarshal(body, &fields))
+
+	require.ElementsMatch(t, []string{"userId", "username", "firstName", "profileImageId"}, keysOf(fields))
+}
+
+func Test_UserResponse_Should_Keep_All_Fields_Without_Selection(t *testing.T) {
+	response := cfm.CreateUserResponse(givenStoredUser(), cfe.UserViewFull)
+	response.SelectFields(nil)
+
+	require.NotNil(t, response.EmailAddress)
+	require.NotNil(t, response.AccountType)
+}
+
+func Test_GetUserAttributes_Should_Project_Selected_Fields(t *testing.T) {
+	require.Nil(t, cfm.GetUserAttributes(nil))
+
+	attributes := cfm.GetUserAttributes([]string{"username", "primaryAddress"})
+
+	require.ElementsMatch(t, []string{"UserId", "IdentitySubject", "UpdatedDate", "Username", "PrimaryAddress"}, attributes)
+}
+
+func Test_GetUser_Should_Reject_Fields_Outside_Allowlist(t *testing.T) {
+	request := &cfgu.GetUserRequest{
+		UserId: "jdoe",
+		Fields: []string{"username", "GSI1PK"},
+	}
+
+	err := Validate.Struct(request)
+
+	require.NotNil(t, err)
+	AssertValidationError(t, err, "Fields[1]", "is_user_field")
+
+	request.Fields = []string{"username", "firstName"}
+	require.Nil(t, Validate.Struct(request))
+}
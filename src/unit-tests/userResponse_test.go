@@ -0,0 +1,93 @@
+package unittest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func givenStoredUser() *cfm.User {
+	pk := "USER#01J0000000000000000000000"
+	firstName := "John"
+	lastName := "Doe"
+	phoneNumber := "8011239088"
+	city := "Salt Lake City"
+	biography := "Short bio about the user."
+	profileImageId := "01J0000000000000000000001"
+
+	return &cfm.User{
+		PK:             &pk,
+		SK:             &pk,
+		GSI1PK:         &pk,
+		UserId:         "01J0000000000000000000000",
+		Username:       "jdoe",
+		FirstName:      &firstName,
+		LastName:       &lastName,
+		PhoneNumber:    &phoneNumber,
+		EmailAddress:   "jdoe@example.com",
+		PrimaryAddress: &cfm.Address{City: &city},
+		ProfileImageId: &profileImageId,
+		Biography:      &biography,
+		AccountType:    cfe.BusinessAccount,
+		CreatedDate:    time.Now(),
+		UpdatedDate:    time.Now(),
+	}
+}
+
+func Test_UserResponse_Should_Only_Include_Public_Fields_For_Public_View(t *testing.T) {
+	user := givenStoredUser()
+
+	body, err := json.Marshal(cfm.CreateUserResponse(user, cfe.UserViewPublic))
+	require.Nil(t, err)
+
+	var fields map[string]interface{}
+	require.Nil(t, json.Unmarshal(body, &fields))
+
+	require.ElementsMatch(t, []string{"userId", "username", "firstName", "profileImageId", "biography"}, keysOf(fields))
+}
+
+func Test_UserResponse_Should_Include_Profile_For_Full_View(t *testing.T) {
+	user := givenStoredUser()
+
+	response := cfm.CreateUserResponse(user, cfe.UserViewFull)
+
+	require.Equal(t, user.EmailAddress, *response.EmailAddress)
+	require.Equal(t, user.LastName, response.LastName)
+	require.Equal(t, user.PrimaryAddress, response.PrimaryAddress)
+	require.Equal(t, cfe.BusinessAccount, *response.AccountType)
+	require.Equal(t, user.CreatedDate, *response.CreatedDate)
+}
+
+func Test_User_Should_Not_Serialize_Storage_Keys(t *testing.T) {
+	body, err := json.Marshal(givenStoredUser())
+	require.Nil(t, err)
+
+	require.NotContains(t, string(body), "USER#")
+	require.NotContains(t, string(body), "omitempty")
+}
+
+func keysOf(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func Test_User_Should_Only_Be_Owned_By_Linked_Subject(t *testing.T) {
+	user := givenStoredUser()
+	require.False(t, user.IsOwnedBy("auth0|123"))
+
+	subject := "auth0|123"
+	user.IdentitySubject = &subject
+
+	require.True(t, user.IsOwnedBy("auth0|123"))
+	require.False(t, user.IsOwnedBy("auth0|456"))
+	require.False(t, user.IsOwnedBy(""))
+}
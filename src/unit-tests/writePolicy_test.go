@@ -0,0 +1,54 @@
+package unittest
+
+import (
+	"reflect"
+	"testing"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfucu "cf-user/update-current-user"
+	cfuu "cf-user/update-user"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WritePolicy_Should_Reject_Admin_Fields_Without_Permission(t *testing.T) {
+	username := "jdoe"
+	accountType := cfe.BusinessAccount.String()
+	biography := "Short bio about the user."
+
+	request := cfuu.UpdateUserRequest{
+		Username:    &username,
+		AccountType: &accountType,
+		Biography:   &biography,
+	}
+
+	errors := cfc.CheckWritePermissions(request, cfe.Permissions{cfe.UpdateUser.String()})
+
+	require.Len(t, errors, 2)
+	require.Equal(t, "username", errors[0].Field)
+	require.Equal(t, "accountType", errors[1].Field)
+	require.Equal(t, cfe.AdminUser.String(), errors[1].Permission)
+}
+
+func Test_WritePolicy_Should_Allow_Admin_Fields_With_Permission(t *testing.T) {
+	accountType := cfe.BusinessAccount.String()
+	request := &cfuu.UpdateUserRequest{AccountType: &accountType}
+
+	require.Empty(t, cfc.CheckWritePermissions(request, cfe.Permissions{cfe.AdminUser.String()}))
+	require.Empty(t, cfc.CheckWritePermissions(request, cfe.Permissions{"cf:*:user"}))
+	require.Empty(t, cfc.CheckWritePermissions(request, cfe.Permissions{cfe.SuperAdminPermission}))
+}
+
+func Test_WritePolicy_Should_Allow_Owner_Fields_Without_Permission(t *testing.T) {
+	firstName := "John"
+	biography := "Short bio about the user."
+	request := cfuu.UpdateUserRequest{FirstName: &firstName, Biography: &biography}
+
+	require.Empty(t, cfc.CheckWritePermissions(request, cfe.Permissions{}))
+}
+
+func Test_WritePolicy_Should_Only_Apply_To_Tagged_Requests(t *testing.T) {
+	require.True(t, cfc.HasWritePolicy(reflect.TypeOf(cfuu.UpdateUserRequest{})))
+	require.False(t, cfc.HasWritePolicy(reflect.TypeOf(cfucu.UpdateCurrentUserRequest{})))
+}
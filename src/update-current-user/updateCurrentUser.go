@@ -0,0 +1,74 @@
+package updatecurrentuser
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	cfc "cf-user/core"
+	cfe "cf-user/core/enums"
+	cfm "cf-user/core/models"
+)
+
+type UpdateCurrentUserRequest struct {
+	FirstName      *string      `json:"firstName" validate:"omitempty,max=300" log:"initial"`
+	LastName       *string      `json:"lastName" validate:"omitempty,max=300" log:"initial"`
+	PhoneNumber    *string      `json:"phoneNumber" validate:"omitempty,max=11" log:"mask"` // 10 digit number or 11 digit including country code
+	PrimaryAddress *cfm.Address `json:"primaryAddress" validate:"omitempty"`
+	BillingAddress *cfm.Address `json:"billingAddress" validate:"omitempty"`
+	ProfileImageId *string      `json:"profileImageId" validate:"omitempty,max=26"` // 26 char ULID
+	Biography      *string      `json:"biography" validate:"omitempty,max=4000"`
+}
+
+var LambdaConfig *cfc.LambdaConfig[UpdateCurrentUserRequest, bool]
+
+func InitLambda(ddbStore *cfc.DynamoDbStore) {
+	roleRequired := cfe.UpdateUser
+
+	LambdaConfig = cfc.CreateLambaConfig[UpdateCurrentUserRequest, bool](roleRequired, ddbStore)
+
+	LambdaConfig.FunctionHandler.Decoding = cfc.StrictDecodeOptions()
+
+	// Any caller may update the profile linked to their own identity
+	LambdaConfig.FunctionHandler.AllowWithoutRole()
+
+	LambdaConfig.FunctionHandler.Use(cfc.DryRunMiddleware())
+}
+
+func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request UpdateCurrentUserRequest) (*bool, *cfe.ResponseError) {
+		requesterOid := cfc.RequesterOidFromContext(ctx)
+		if len(requesterOid) == 0 {
+			e := cfe.ErrorAuthorization("No identity was provided for the current user.")
+			return nil, &e
+		}
+
+		user, err := LambdaConfig.DynamoDbStore.GetUserByIdentitySubject(ctx, requesterOid)
+		if err != nil {
+			parseError := cfe.ErrorGetOrDefault(err)
+			return nil, &parseError
+		}
+
+		LambdaConfig.FunctionHandler.Logger.Info("Updating Current User", "UserId", user.UserId)
+
+		updateInput := &cfm.User{
+			Username:       user.Username,
+			AccountType:    user.AccountType,
+			FirstName:      request.FirstName,
+			LastName:       request.LastName,
+			PhoneNumber:    request.PhoneNumber,
+			PrimaryAddress: request.PrimaryAddress,
+			BillingAddress: request.BillingAddress,
+			ProfileImageId: request.ProfileImageId,
+			Biography:      request.Biography,
+		}
+
+		userUpdated, err := LambdaConfig.DynamoDbStore.UpdateUser(ctx, user.UserId, updateInput)
+		if err != nil {
+			parseError := cfe.ErrorGetOrDefault(err)
+			return nil, &parseError
+		}
+
+		return userUpdated, nil
+	}), nil
+}
@@ -2,6 +2,8 @@ package updateidentitygroup
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
 
@@ -11,13 +13,18 @@ import (
 )
 
 type UpdateUserRequest struct {
-	FirstName      *string      `json:"firstName" validate:"omitempty,max=300"`
-	LastName       *string      `json:"lastName" validate:"omitempty,max=300"`
-	PhoneNumber    *string      `json:"phoneNumber" validate:"omitempty,max=11"`    // 10 digit number or 11 digit including country code
-	PrimaryAddress *cfm.Address `json:"primaryAddress" validate:"omitempty"`        // should inherit validation?
-	BillingAddress *cfm.Address `json:"billingAddress" validate:"omitempty"`        // should inherit validation?
-	ProfileImageId *string      `json:"profileImageId" validate:"omitempty,max=26"` // 26 char ULID
+	UserId         string       `json:"-" path:"userId"`
+	Username       *string      `json:"username" validate:"omitempty,max=300" write:"cf:admin:user"`
+	AccountType    *string      `json:"accountType" validate:"omitempty,is_account_type" write:"cf:admin:user"`
+	FirstName      *string      `json:"firstName" validate:"omitempty,max=300" log:"initial"`
+	LastName       *string      `json:"lastName" validate:"omitempty,max=300" log:"initial"`
+	PhoneNumber    *string      `json:"phoneNumber" validate:"omitempty,max=11" log:"mask"` // 10 digit number or 11 digit including country code
+	PrimaryAddress *cfm.Address `json:"primaryAddress" validate:"omitempty"`                // should inherit validation?
+	BillingAddress *cfm.Address `json:"billingAddress" validate:"omitempty"`                // should inherit validation?
+	ProfileImageId *string      `json:"profileImageId" validate:"omitempty,max=26"`         // 26 char ULID
 	Biography      *string      `json:"biography" validate:"omitempty,max=4000"`
+	// Links the user to an identity provider subject, for users created before they signed in
+	IdentitySubject *string `json:"identitySubject" validate:"omitempty,min=1,max=255" log:"mask" write:"cf:admin:user"`
 }
 
 var LambdaConfig *cfc.LambdaConfig[UpdateUserRequest, bool]
@@ -26,25 +33,109 @@ func InitLambda(ddbStore *cfc.DynamoDbStore) {
 	roleRequired := cfe.UpdateUser
 
 	LambdaConfig = cfc.CreateLambaConfig[UpdateUserRequest, bool](roleRequired, ddbStore)
+
+	LambdaConfig.FunctionHandler.Validate.RegisterValidation(cfe.GetAccountTypeValidator())
+
+	LambdaConfig.FunctionHandler.Decoding = cfc.StrictDecodeOptions()
+
+	LambdaConfig.FunctionHandler.AllowOwner(cfc.UserOwnerCheck(LambdaConfig.DynamoDbStore))
+
+	// Owners sending back their profile as read must not need cf:admin:user for the username and account type
+	LambdaConfig.FunctionHandler.ClearUnchangedWith(clearUnchangedAdminFields)
+
+	LambdaConfig.FunctionHandler.Use(cfc.DryRunMiddleware())
 }
 
-func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return LambdaConfig.FunctionHandler.HandleRequest(apiRequest, func(request UpdateUserRequest) (*bool, *cfe.ResponseError) {
-		userId, ok := apiRequest.PathParameters["userId"]
-		if !ok {
-			e := cfe.ErrorValidation("missing path parameter userId")
-			return nil, &e
+func clearUnchangedAdminFields(ctx context.Context, request *UpdateUserRequest) error {
+	if request.Username == nil && request.AccountType == nil {
+		return nil
+	}
+
+	user, err := LambdaConfig.DynamoDbStore.GetUser(ctx, request.UserId)
+	if err != nil {
+		var respError cfe.ResponseError
+		if errors.As(err, &respError) && respError.ErrorCode == cfe.ErrorCodeNotFound.String() {
+			return nil
+		}
+
+		return err
+	}
+
+	if request.Username != nil && *request.Username == user.Username {
+		request.Username = nil
+	}
+
+	if request.AccountType != nil {
+		accountType, err := cfe.GetAccountType(request.AccountType)
+		if err == nil && *accountType == user.AccountType {
+			request.AccountType = nil
 		}
+	}
+
+	return nil
+}
+
+func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return LambdaConfig.FunctionHandler.HandleRequest(ctx, apiRequest, func(ctx context.Context, request UpdateUserRequest) (*bool, *cfe.ResponseError) {
+		userId := request.UserId
 
 		LambdaConfig.FunctionHandler.Logger.Info("Updating User", "UserId", userId)
 
-		user, _ := LambdaConfig.DynamoDbStore.GetUser(userId)
+		user, _ := LambdaConfig.DynamoDbStore.GetUser(ctx, userId)
 		if user == nil {
 			e := cfe.ErrorNotFound()
 			return nil, &e
 		}
 
+		username := user.Username
+		if request.Username != nil && *request.Username != user.Username {
+			existingUser, err := LambdaConfig.DynamoDbStore.GetUserByUsername(ctx, *request.Username)
+			if err != nil {
+				var respError cfe.ResponseError
+				if !errors.As(err, &respError) || respError.ErrorCode != cfe.ErrorCodeNotFound.String() {
+					parseError := cfe.ErrorGetOrDefault(err)
+					return nil, &parseError
+				}
+			}
+			if existingUser != nil {
+				e := cfe.ErrorValidation(fmt.Sprintf("User already exists with given username: %v", *request.Username))
+				return nil, &e
+			}
+
+			username = *request.Username
+		}
+
+		if request.IdentitySubject != nil && !user.IsOwnedBy(*request.IdentitySubject) {
+			existingUser, err := LambdaConfig.DynamoDbStore.GetUserByIdentitySubject(ctx, *request.IdentitySubject, "UserId")
+			if err != nil {
+				var respError cfe.ResponseError
+				if !errors.As(err, &respError) || respError.ErrorCode != cfe.ErrorCodeNotFound.String() {
+					parseError := cfe.ErrorGetOrDefault(err)
+					return nil, &parseError
+				}
+			}
+			if existingUser != nil {
+				e := cfe.ErrorValidation("User already exists for the given identity subject.")
+				return nil, &e
+			}
+
+			if _, err := LambdaConfig.DynamoDbStore.LinkIdentitySubject(ctx, userId, user.IdentitySubject, *request.IdentitySubject); err != nil {
+				parseError := cfe.ErrorGetOrDefault(err)
+				return nil, &parseError
+			}
+		}
+
+		accountType := user.AccountType
+		if request.AccountType != nil {
+			aType, err := cfe.GetAccountType(request.AccountType)
+			if err == nil {
+				accountType = *aType
+			}
+		}
+
 		updateInput := &cfm.User{
+			Username:       username,
+			AccountType:    accountType,
 			FirstName:      request.FirstName,
 			LastName:       request.LastName,
 			PhoneNumber:    request.PhoneNumber,
@@ -54,7 +145,14 @@ func Handler(ctx context.Context, apiRequest events.APIGatewayProxyRequest) (eve
 			Biography:      request.Biography,
 		}
 
-		groupUpdated, err := LambdaConfig.DynamoDbStore.UpdateUser(userId, updateInput)
+		var groupUpdated *bool
+		var err error
+		// Renames also move the username claim, which rejects usernames taken since the check above
+		if username != user.Username {
+			groupUpdated, err = LambdaConfig.DynamoDbStore.RenameUser(ctx, userId, user.Username, updateInput)
+		} else {
+			groupUpdated, err = LambdaConfig.DynamoDbStore.UpdateUser(ctx, userId, updateInput)
+		}
 
 		if err != nil {
 			parseError := cfe.ErrorGetOrDefault(err)